@@ -1,15 +1,39 @@
 package aws
 
 import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	profileNameEnvVar = "AWS_PROFILE"
+	profileNameEnvVar      = "AWS_PROFILE"
+	regionEnvVar           = "AWS_REGION"
+	endpointURLEnvVar      = "AWS_ENDPOINT_URL"
+	dynamoDBEndpointEnvVar = "AWS_ENDPOINT_URL_DYNAMODB"
 )
 
+// credentials are refreshed this long before they expire, so long running exports never use the expired ones
+const credentialsExpiryWindow = time.Minute
+
+// SessionParams represents the AWS connection settings set by the user, empty values fallback to the corresponding
+// env vars, and then to the shared config.
+type SessionParams struct {
+	Profile     string
+	Region      string
+	EndpointURL string
+	// RoleARN is the role assumed using the profile credentials, with the optional ExternalID and MFASerial (in which
+	// case the MFA token code is prompted from stdin), SessionDuration is the role session lifetime.
+	RoleARN         string
+	ExternalID      string
+	MFASerial       string
+	SessionDuration time.Duration
+}
+
 type runtime int
 
 const (
@@ -24,13 +48,36 @@ const (
 	homeEnvName         = "HOME"
 )
 
-// GetSession returns the AWS session for the corresponding profile.
-func GetSession(profile string) *session.Session {
-	p := profile
-	if profile == "" {
-		p = getEnvProfileNameOrDefault()
+var sessions = struct {
+	sync.Mutex
+	cache map[SessionParams]*session.Session
+}{cache: make(map[SessionParams]*session.Session)}
+
+// GetSession returns the AWS session for the corresponding profile, region and endpoint. The sessions are cached by
+// the settings, so the commands calling it more than once share the credentials, i.e. the MFA token code is prompted
+// only once, and the assumed role credentials are reused until they are about to expire.
+func GetSession(params *SessionParams) *session.Session {
+	resolved := *params
+	if resolved.Profile == "" {
+		resolved.Profile = getEnvProfileNameOrDefault()
+	}
+	if resolved.Region == "" {
+		resolved.Region = os.Getenv(regionEnvVar)
+	}
+	if resolved.EndpointURL == "" {
+		resolved.EndpointURL = getEnvEndpointURL()
+	}
+	sessions.Lock()
+	defer sessions.Unlock()
+	if sess, ok := sessions.cache[resolved]; ok {
+		return sess
+	}
+	sess := setupSession(resolved.Profile, resolved.Region, resolved.EndpointURL, resolved.SessionDuration)
+	if resolved.RoleARN != "" {
+		sess = assumeRole(sess, &resolved)
 	}
-	return setupSession(p)
+	sessions.cache[resolved] = sess
+	return sess
 }
 
 func getEnvProfileNameOrDefault() string {
@@ -41,18 +88,56 @@ func getEnvProfileNameOrDefault() string {
 	return profile
 }
 
-func setupSession(profile string) *session.Session {
+// DynamoDB specific endpoint takes precedence over the one set for all the services.
+func getEnvEndpointURL() string {
+	if endpoint := os.Getenv(dynamoDBEndpointEnvVar); endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv(endpointURLEnvVar)
+}
+
+func setupSession(profile string, region string, endpoint string, duration time.Duration) *session.Session {
 	r := detectRuntime()
 	if r == snap {
 		setActualUserHome()
 	}
+	config := aws.Config{}
+	if region != "" {
+		config.Region = aws.String(region)
+	}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+	}
 	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		Config:            config,
 		Profile:           profile,
 		SharedConfigState: session.SharedConfigEnable,
+		// used if the profile itself assumes the role (i.e. has role_arn and mfa_serial set in the shared config)
+		AssumeRoleTokenProvider: stscreds.StdinTokenProvider,
+		AssumeRoleDuration:      duration,
 	}))
 	return sess
 }
 
+// Returns the copy of the session which uses the credentials of the assumed role, the credentials are refreshed
+// automatically once they are about to expire, i.e. for the exports running longer than the role session lifetime.
+func assumeRole(sess *session.Session, params *SessionParams) *session.Session {
+	creds := stscreds.NewCredentials(sess, params.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		if params.ExternalID != "" {
+			p.ExternalID = aws.String(params.ExternalID)
+		}
+		if params.MFASerial != "" {
+			p.SerialNumber = aws.String(params.MFASerial)
+			p.TokenProvider = stscreds.StdinTokenProvider
+		}
+		if params.SessionDuration != 0 {
+			p.Duration = params.SessionDuration
+		}
+		p.ExpiryWindow = credentialsExpiryWindow
+	})
+	return sess.Copy(&aws.Config{Credentials: creds})
+}
+
 func detectRuntime() runtime {
 	if isEnvSet(snapEnvName) || isEnvSet(snapNameEnvName) || isEnvSet(snapRevisionEnvName) {
 		return snap
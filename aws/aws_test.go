@@ -105,7 +105,7 @@ func TestGetSession(t *testing.T) {
 		for k, v := range tt.envs {
 			_ = os.Setenv(k, v)
 		}
-		session := GetSession("dynocsv")
+		session := GetSession(&SessionParams{Profile: "dynocsv"})
 		// restore HOME back to its original value
 		_ = os.Setenv(homeEnvName, home)
 		for k := range tt.envs {
@@ -116,3 +116,101 @@ func TestGetSession(t *testing.T) {
 		}
 	}
 }
+
+func TestGetSessionRegionAndEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   *SessionParams
+		envs     map[string]string
+		region   string
+		endpoint string
+	}{
+		{
+			name:     "region and endpoint from params",
+			params:   &SessionParams{Profile: "dynocsv", Region: "eu-west-1", EndpointURL: "http://localhost:8000"},
+			envs:     map[string]string{regionEnvVar: "us-west-2", endpointURLEnvVar: "http://localhost:4566"},
+			region:   "eu-west-1",
+			endpoint: "http://localhost:8000",
+		},
+		{
+			name:     "region and endpoint from env vars",
+			params:   &SessionParams{Profile: "dynocsv"},
+			envs:     map[string]string{regionEnvVar: "us-west-2", endpointURLEnvVar: "http://localhost:4566"},
+			region:   "us-west-2",
+			endpoint: "http://localhost:4566",
+		},
+		{
+			name:   "dynamodb endpoint env var takes precedence",
+			params: &SessionParams{Profile: "dynocsv"},
+			envs: map[string]string{
+				endpointURLEnvVar:      "http://localhost:4566",
+				dynamoDBEndpointEnvVar: "http://localhost:8000"},
+			region:   "us-east-1",
+			endpoint: "http://localhost:8000",
+		},
+		{
+			name:     "region from shared config",
+			params:   &SessionParams{Profile: "dynocsv"},
+			envs:     map[string]string{},
+			region:   "us-east-1",
+			endpoint: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, _ := os.Getwd()
+			home := os.ExpandEnv("$" + homeEnvName)
+			_ = os.Setenv(homeEnvName, dir)
+			for k, v := range tt.envs {
+				_ = os.Setenv(k, v)
+			}
+			session := GetSession(tt.params)
+			// restore HOME back to its original value
+			_ = os.Setenv(homeEnvName, home)
+			for k := range tt.envs {
+				_ = os.Unsetenv(k)
+			}
+			if got := aws.StringValue(session.Config.Region); got != tt.region {
+				t.Errorf("Region = %v, does not match %v", got, tt.region)
+			}
+			if got := aws.StringValue(session.Config.Endpoint); got != tt.endpoint {
+				t.Errorf("Endpoint = %v, does not match %v", got, tt.endpoint)
+			}
+		})
+	}
+}
+
+func TestGetSessionAssumeRole(t *testing.T) {
+	dir, _ := os.Getwd()
+	home := os.ExpandEnv("$" + homeEnvName)
+	_ = os.Setenv(homeEnvName, dir)
+	session := GetSession(&SessionParams{Profile: "dynocsv", RoleARN: "arn:aws:iam::123456789012:role/dynocsv"})
+	base := GetSession(&SessionParams{Profile: "dynocsv"})
+	// restore HOME back to its original value
+	_ = os.Setenv(homeEnvName, home)
+	if session.Config.Credentials == base.Config.Credentials {
+		t.Errorf("Credentials of the assumed role are the same as the profile ones")
+	}
+	if got := aws.StringValue(session.Config.Region); got != "us-east-1" {
+		t.Errorf("Region = %v, does not match %v", got, "us-east-1")
+	}
+}
+
+func TestGetSessionCached(t *testing.T) {
+	dir, _ := os.Getwd()
+	home := os.ExpandEnv("$" + homeEnvName)
+	_ = os.Setenv(homeEnvName, dir)
+	params := SessionParams{Profile: "dynocsv", RoleARN: "arn:aws:iam::123456789012:role/dynocsv", MFASerial: "mfa"}
+	session := GetSession(&params)
+	same := params
+	again := GetSession(&same)
+	other := GetSession(&SessionParams{Profile: "dynocsv", RoleARN: "arn:aws:iam::210987654321:role/dynocsv"})
+	// restore HOME back to its original value
+	_ = os.Setenv(homeEnvName, home)
+	if again != session {
+		t.Errorf("GetSession() with the same settings returned the new session")
+	}
+	if other == session || other.Config.Credentials == session.Config.Credentials {
+		t.Errorf("GetSession() with the other role returned the same credentials")
+	}
+}
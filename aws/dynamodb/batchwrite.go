@@ -0,0 +1,124 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"sync"
+	"time"
+)
+
+const (
+	batchWriteItemLimit      = 25
+	batchWriteItemMaxRetries = 10
+)
+
+// batchWriteItemBackoff is the delay before the first retry of the unprocessed items, doubled on each next retry.
+var batchWriteItemBackoff = 100 * time.Millisecond
+
+// rateLimiter spreads the writes evenly, so at most rate items are written per second (0 means no limit). It is safe to
+// share it between the batch writers running concurrently.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	start   time.Time
+	written int64
+}
+
+// wait waits until the next n items are allowed to be written.
+func (l *rateLimiter) wait(n int) {
+	if l.rate <= 0 {
+		return
+	}
+	l.mu.Lock()
+	if l.start.IsZero() {
+		l.start = time.Now()
+	}
+	next := l.start.Add(time.Duration(float64(l.written) / l.rate * float64(time.Second)))
+	l.written += int64(n)
+	l.mu.Unlock()
+	if d := time.Until(next); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// batchWriter writes (or deletes) the items of the table using BatchWriteItem in the batches of 25 items, retrying the
+// unprocessed items with the exponential backoff. The batch is written early if the item with the same key is already
+// in it, as BatchWriteItem rejects the batch with the duplicate keys.
+type batchWriter struct {
+	svc           dynamodbiface.DynamoDBAPI
+	table         string
+	keyAttributes []string
+	limiter       *rateLimiter
+	requests      []*dynamodb.WriteRequest
+	keys          map[string]bool
+	written       int64
+}
+
+func newBatchWriter(
+	svc dynamodbiface.DynamoDBAPI, desc *dynamodb.TableDescription, table string, limiter *rateLimiter) *batchWriter {
+
+	return &batchWriter{
+		svc:           svc,
+		table:         table,
+		keyAttributes: keyAttributeNames(desc),
+		limiter:       limiter,
+		keys:          make(map[string]bool),
+	}
+}
+
+// put adds the item into the batch, and writes the batch once it is full.
+func (w *batchWriter) put(item map[string]*dynamodb.AttributeValue) error {
+	return w.add(item, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+}
+
+// delete adds the delete of the item by its key into the batch, and writes the batch once it is full.
+func (w *batchWriter) delete(key map[string]*dynamodb.AttributeValue) error {
+	return w.add(key, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: key}})
+}
+
+func (w *batchWriter) add(key map[string]*dynamodb.AttributeValue, request *dynamodb.WriteRequest) error {
+	id := keyID(key, w.keyAttributes)
+	if w.keys[id] {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	w.keys[id] = true
+	w.requests = append(w.requests, request)
+	if len(w.requests) == batchWriteItemLimit {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush writes the batch.
+func (w *batchWriter) flush() error {
+	if len(w.requests) == 0 {
+		return nil
+	}
+	requests := map[string][]*dynamodb.WriteRequest{w.table: w.requests}
+	backoff := batchWriteItemBackoff
+	for retry := 0; len(requests[w.table]) != 0; retry++ {
+		if retry > 0 {
+			if retry > batchWriteItemMaxRetries {
+				return fmt.Errorf("failed to write %d items into table %s after %d retries",
+					len(requests[w.table]), w.table, batchWriteItemMaxRetries)
+			}
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		w.limiter.wait(len(requests[w.table]))
+		result, err := w.svc.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: requests})
+		if err != nil {
+			return err
+		}
+		w.written += int64(len(requests[w.table]) - len(result.UnprocessedItems[w.table]))
+		requests = result.UnprocessedItems
+	}
+	w.requests = nil
+	w.keys = make(map[string]bool)
+	return nil
+}
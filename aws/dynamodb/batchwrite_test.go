@@ -0,0 +1,86 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type batchWriteDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	requests []int
+	items    []map[string]*dynamodb.AttributeValue
+}
+
+// BatchWriteItem leaves the last item of each request with more than one item unprocessed.
+func (m *batchWriteDynamoDBClient) BatchWriteItem(
+	input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+
+	requests := input.RequestItems["orders"]
+	m.requests = append(m.requests, len(requests))
+	output := &dynamodb.BatchWriteItemOutput{}
+	if len(requests) > 1 {
+		output.UnprocessedItems = map[string][]*dynamodb.WriteRequest{"orders": requests[len(requests)-1:]}
+		requests = requests[:len(requests)-1]
+	}
+	for _, request := range requests {
+		m.items = append(m.items, request.PutRequest.Item)
+	}
+	return output, nil
+}
+
+func order(customerID string, orderID string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"customerId": {S: aws.String(customerID)},
+		"orderId":    {N: aws.String(orderID)},
+	}
+}
+
+func TestBatchWriter(t *testing.T) {
+	batchWriteItemBackoff = time.Millisecond
+	svc := &batchWriteDynamoDBClient{}
+	w := newBatchWriter(svc, ordersDescription, "orders", &rateLimiter{})
+	items := []map[string]*dynamodb.AttributeValue{order("c1", "1"), order("c1", "2"), order("c1", "1.0")}
+	for _, item := range items {
+		if err := w.put(item); err != nil {
+			t.Fatalf("put() error = %v", err)
+		}
+	}
+	if err := w.flush(); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+	// the duplicate key "1.0" flushes the first two items early, the second item is retried as unprocessed
+	if want := []int{2, 1, 1}; !reflect.DeepEqual(svc.requests, want) {
+		t.Errorf("flush() requests = %v, want %v", svc.requests, want)
+	}
+	if !reflect.DeepEqual(svc.items, items) {
+		t.Errorf("flush() items = %v, want %v", svc.items, items)
+	}
+	if w.written != 3 {
+		t.Errorf("written = %d, want 3", w.written)
+	}
+}
+
+func TestBatchWriterLimit(t *testing.T) {
+	batchWriteItemBackoff = time.Millisecond
+	svc := &batchWriteDynamoDBClient{}
+	w := newBatchWriter(svc, ordersDescription, "orders", &rateLimiter{})
+	for i := 0; i < 30; i++ {
+		if err := w.put(order("c1", strconv.Itoa(i))); err != nil {
+			t.Fatalf("put() error = %v", err)
+		}
+	}
+	if err := w.flush(); err != nil {
+		t.Fatalf("flush() error = %v", err)
+	}
+	if want := []int{25, 1, 5, 1}; !reflect.DeepEqual(svc.requests, want) {
+		t.Errorf("flush() requests = %v, want %v", svc.requests, want)
+	}
+	if w.written != 30 {
+		t.Errorf("written = %d, want 30", w.written)
+	}
+}
@@ -0,0 +1,222 @@
+package dynamodb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	exportBatchSize      = 100
+	exportManifestPrefix = "manifest-"
+	exportJSONFormat     = ".json"
+	exportIonFormat      = ".ion"
+	gzipSuffix           = ".gz"
+)
+
+// ExportFiles returns the data files (.json.gz, .ion.gz, .json or .ion) of DynamoDB's export to S3 found in the
+// directory (i.e. the downloaded AWSDynamoDB/<export id>/data) and its subdirectories in the order of their paths, or
+// the path itself if it is the file. The manifest files are skipped.
+func ExportFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	files := make([]string, 0)
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), exportManifestPrefix) || exportFormat(file) == "" {
+			return nil
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .json.gz or .ion.gz export files found in %s", path)
+	}
+	return files, nil
+}
+
+// exportFormat returns the format of the export file by its extension, or "" if it is not the export file.
+func exportFormat(file string) string {
+	ext := filepath.Ext(strings.TrimSuffix(file, gzipSuffix))
+	if ext == exportJSONFormat || ext == exportIonFormat {
+		return ext
+	}
+	return ""
+}
+
+// ConvertExport converts the items of DynamoDB's export to S3 files into the CSV outputs locally, the same as the
+// export does with the items read from the table. The key attributes of the schema (if it is set) come first, then the
+// attributes of the first item, and then the ones detected later. It returns the CSV headers of the outputs (by their
+// keys) which got new attributes detected after the CSV headers have been already written.
+func ConvertExport(
+	files []string,
+	schema *TableSchema,
+	columns string,
+	skipColumns string,
+	limit uint,
+	writers Writers) (map[string][]string, error) {
+
+	e := newExporter(columns, skipColumns, limit, writers)
+	if columns == "" && schema != nil {
+		e.attributes, e.attributesSet = schema.baselineAttributes(e.skipAttributes)
+		e.keyAttributes, _ = appendKeyAttributes(
+			keySchemaElements(schema.KeySchema, make(map[string]string)), nil, make(map[string]bool), e.skipAttributes)
+	}
+	started := false
+	start := func(item map[string]*dynamodb.AttributeValue) {
+		started = true
+		if columns == "" && item != nil {
+			e.attributes, e.attributesSet = appendItemAttributes(item, e.attributes, e.attributesSet, e.skipAttributes)
+		}
+		for _, key := range writers.Initial() {
+			e.sink(key)
+		}
+	}
+	items := make([]map[string]*dynamodb.AttributeValue, 0, exportBatchSize)
+	done := false
+	for _, file := range files {
+		err := readExportFile(file, func(item map[string]*dynamodb.AttributeValue) bool {
+			if !started {
+				start(item)
+			}
+			items = append(items, item)
+			if len(items) == exportBatchSize {
+				done = e.process(items, false)
+				items = items[:0]
+			}
+			return !done
+		})
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return e.extendedHeaders(), nil
+		}
+	}
+	if !started {
+		start(nil)
+	}
+	e.process(items, true)
+	return e.extendedHeaders(), nil
+}
+
+// baselineAttributes returns the table's key attributes, and the global secondary indexes' ones in the order of the
+// indexes names, the same as they come first in the export of the table.
+func (s *TableSchema) baselineAttributes(skipAttributes map[string]bool) ([]string, map[string]bool) {
+	attributes, attributesSet := appendKeyAttributes(
+		keySchemaElements(s.KeySchema, make(map[string]string)), make([]string, 0), make(map[string]bool),
+		skipAttributes)
+	indexes := append([]IndexSchema(nil), s.GlobalSecondaryIndexes...)
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+	for _, index := range indexes {
+		attributes, attributesSet = appendKeyAttributes(
+			keySchemaElements(index.KeySchema, make(map[string]string)), attributes, attributesSet, skipAttributes)
+	}
+	return attributes, attributesSet
+}
+
+// appendItemAttributes appends the item's attributes, which are not there yet, in the alphabetical order.
+func appendItemAttributes(
+	item map[string]*dynamodb.AttributeValue,
+	attributes []string,
+	attributesSet map[string]bool,
+	skipAttributes map[string]bool) ([]string, map[string]bool) {
+
+	rest := make([]string, 0, len(item))
+	for k, av := range item {
+		if _, handled := getValue(av); handled && shouldAppendAttribute(k, attributesSet, skipAttributes) {
+			attributesSet[k] = true
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(attributes, rest...), attributesSet
+}
+
+// readExportFile reads the items of the export file (gzipped if it has .gz extension) in DynamoDB JSON or Amazon Ion
+// format, i.e. {"Item":{"id":{"S":"1"}}} or {Item:{id:"1"}} per line, until fn returns false.
+func readExportFile(file string, fn func(item map[string]*dynamodb.AttributeValue) bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(file, gzipSuffix) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	next := jsonExportItems(r)
+	if exportFormat(file) == exportIonFormat {
+		next = ionExportItems(r)
+	}
+	for {
+		item, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		if !fn(item) {
+			return nil
+		}
+	}
+}
+
+// exportItem is the line of the export to S3 in DynamoDB JSON format, the attribute values field names are the same
+// as of dynamodb.AttributeValue, and the binary values are base64 encoded.
+type exportItem struct {
+	Item map[string]*dynamodb.AttributeValue
+}
+
+func jsonExportItems(r io.Reader) func() (map[string]*dynamodb.AttributeValue, error) {
+	decoder := json.NewDecoder(r)
+	return func() (map[string]*dynamodb.AttributeValue, error) {
+		var item exportItem
+		if err := decoder.Decode(&item); err != nil {
+			return nil, err
+		}
+		if item.Item == nil {
+			return nil, fmt.Errorf("line has no \"Item\"")
+		}
+		return item.Item, nil
+	}
+}
+
+func ionExportItems(r io.Reader) func() (map[string]*dynamodb.AttributeValue, error) {
+	reader := newIonReader(r)
+	return func() (map[string]*dynamodb.AttributeValue, error) {
+		av, err := reader.next()
+		if err != nil {
+			return nil, err
+		}
+		if av.M == nil || av.M["Item"] == nil || av.M["Item"].M == nil {
+			return nil, fmt.Errorf("value has no \"Item\" struct")
+		}
+		return av.M["Item"].M, nil
+	}
+}
@@ -0,0 +1,125 @@
+package dynamodb
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeExportFile writes the data into the file of the export, gzipped if the name has .gz extension.
+func writeExportFile(t *testing.T, dir string, name string, data string) {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if filepath.Ext(name) != gzipSuffix {
+		if _, err := file.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	gz := gzip.NewWriter(file)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeExportFile(t, dir, "manifest-summary.json", `{"version":"2020-06-30"}`)
+	writeExportFile(t, dir, "data/a.json.gz", `{"Item":{"orderId":{"N":"1"},"customerId":{"S":"c1"},"total":{"N":"10"}}}
+{"Item":{"orderId":{"N":"2"},"customerId":{"S":"c1"},"tags":{"SS":["a","b"]}}}
+`)
+	writeExportFile(t, dir, "data/b.ion.gz", `$ion_1_0 {Item:{orderId:3.,customerId:"c2",status:"NEW"}}
+`)
+	writeExportFile(t, dir, "data/_started", "")
+	files, err := ExportFiles(dir)
+	if err != nil {
+		t.Fatalf("ExportFiles() error = %v", err)
+	}
+	wantFiles := []string{filepath.Join(dir, "data/a.json.gz"), filepath.Join(dir, "data/b.ion.gz")}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Fatalf("ExportFiles() = %v, want %v", files, wantFiles)
+	}
+	tests := []struct {
+		name    string
+		schema  *TableSchema
+		limit   uint
+		header  []string
+		records [][]string
+	}{
+		{
+			name:   "schema",
+			schema: ordersSchema,
+			header: []string{"customerId", "orderId", "status", "total", "tags"},
+			records: [][]string{
+				{"c1", "1", "", "10", ""},
+				{"c1", "2", "", "", "[a,b]"},
+				{"c2", "3", "NEW", "", ""},
+			},
+		},
+		{
+			name:   "no schema",
+			limit:  2,
+			header: []string{"customerId", "orderId", "total", "tags"},
+			records: [][]string{
+				{"c1", "1", "10", ""},
+				{"c1", "2", "", "[a,b]"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &recordingWriter{}
+			headers, err := ConvertExport(files, tt.schema, "", "", tt.limit, SingleWriter(writer))
+			if err != nil {
+				t.Fatalf("ConvertExport() error = %v", err)
+			}
+			if len(headers) != 0 {
+				t.Errorf("ConvertExport() headers = %v, want none", headers)
+			}
+			if !reflect.DeepEqual(writer.header, tt.header) {
+				t.Errorf("ConvertExport() header = %v, want %v", writer.header, tt.header)
+			}
+			if !reflect.DeepEqual(writer.records, tt.records) {
+				t.Errorf("ConvertExport() records = %v, want %v", writer.records, tt.records)
+			}
+		})
+	}
+}
+
+func TestConvertExportInvalidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeExportFile(t, dir, "a.json", `{"Item":{"orderId":{"N":"1"}}}
+{"orderId":{"N":"2"}}
+`)
+	files, err := ExportFiles(dir)
+	if err != nil {
+		t.Fatalf("ExportFiles() error = %v", err)
+	}
+	if _, err := ConvertExport(files, nil, "", "", 0, SingleWriter(&recordingWriter{})); err == nil {
+		t.Errorf("ConvertExport() error = nil, want error for line without Item")
+	}
+	if _, err := ExportFiles(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("ExportFiles() error = nil, want error for missing path")
+	}
+}
@@ -0,0 +1,209 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+const transformSeparator = "="
+
+// CopyParams represents what is copied from the source table (or index) into the destination table: the query (or the
+// scan split into the number of segments run in parallel if the query is empty), the filter, and the transform applied
+// to each item. At most Rate items are written per second (0 means no limit). If State is set, the progress is saved
+// into the file after each page, so the interrupted copy is resumed from where it has stopped.
+type CopyParams struct {
+	Table     string
+	Index     string
+	Query     *QueryParams
+	Filter    Filter
+	Segments  uint
+	ToTable   string
+	Transform *Transform
+	Rate      float64
+	State     string
+}
+
+// Transform represents the changes applied to each item copied: the attributes are dropped first, then renamed, and
+// then set to the constant values.
+type Transform struct {
+	Drop   []string
+	Rename map[string]string
+	Set    map[string]*dynamodb.AttributeValue
+}
+
+// ParseTransform parses the attributes to drop, to rename, i.e. "old=new", and to set, i.e. "env=dev", where the type
+// of the value is inferred the same as by the import.
+func ParseTransform(drop []string, rename []string, set []string) (*Transform, error) {
+	t := &Transform{
+		Drop:   drop,
+		Rename: make(map[string]string, len(rename)),
+		Set:    make(map[string]*dynamodb.AttributeValue, len(set)),
+	}
+	for _, r := range rename {
+		parts := strings.SplitN(r, transformSeparator, 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid rename %q, expected <attribute>=<new attribute>", r)
+		}
+		t.Rename[parts[0]] = parts[1]
+	}
+	for _, s := range set {
+		parts := strings.SplitN(s, transformSeparator, 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid set %q, expected <attribute>=<value>", s)
+		}
+		t.Set[parts[0]] = inferAttributeValue(parts[1])
+	}
+	return t, nil
+}
+
+func (t *Transform) apply(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if t == nil {
+		return item
+	}
+	for _, attribute := range t.Drop {
+		delete(item, attribute)
+	}
+	if len(t.Rename) != 0 {
+		renamed := make(map[string]*dynamodb.AttributeValue, len(item))
+		for attribute, av := range item {
+			if name, ok := t.Rename[attribute]; ok {
+				attribute = name
+			}
+			renamed[attribute] = av
+		}
+		item = renamed
+	}
+	for attribute, av := range t.Set {
+		item[attribute] = av
+	}
+	return item
+}
+
+// copySegment is the progress of the single scan segment or query, LastEvaluatedKey is the key the next page starts
+// after, and all the items before it are already written.
+type copySegment struct {
+	LastEvaluatedKey map[string]*dynamodb.AttributeValue `json:"lastEvaluatedKey,omitempty"`
+	Done             bool                                `json:"done"`
+	Copied           int64                               `json:"copied"`
+}
+
+// copyState is the progress of the copy saved into the file (if set), so it could be resumed.
+type copyState struct {
+	mu       sync.Mutex
+	path     string
+	Table    string         `json:"table"`
+	ToTable  string         `json:"toTable"`
+	Segments []*copySegment `json:"segments"`
+}
+
+// loadCopyState loads the copy progress from the file, or starts the new one if the file doesn't exist yet.
+func loadCopyState(path string, table string, toTable string, segments int) (*copyState, error) {
+	state := &copyState{path: path, Table: table, ToTable: toTable}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, state); err != nil {
+				return nil, fmt.Errorf("failed to read copy state %s: %v", path, err)
+			}
+			if state.Table != table || state.ToTable != toTable || len(state.Segments) != segments {
+				return nil, fmt.Errorf("copy state %s is of the copy from %s into %s with %d segments, but it is "+
+					"from %s into %s with %d segments", path, state.Table, state.ToTable, len(state.Segments),
+					table, toTable, segments)
+			}
+			return state, nil
+		}
+	}
+	state.Segments = make([]*copySegment, segments)
+	for i := range state.Segments {
+		state.Segments[i] = &copySegment{}
+	}
+	return state, nil
+}
+
+// update records the page of the segment is written, and saves the progress.
+func (s *copyState) update(
+	segment *copySegment, lastEvaluatedKey map[string]*dynamodb.AttributeValue, copied int64) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	segment.LastEvaluatedKey = lastEvaluatedKey
+	segment.Done = len(lastEvaluatedKey) == 0
+	segment.Copied += copied
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFile(s.path, s)
+}
+
+// writeJSONFile writes v as the indented JSON into the file, the temp file is written first, so the file is never left
+// half written.
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CopyTable copies the items from the source table into the destination table, which could be in the different
+// account or region, streaming the attribute values as they are, and returns the number of the items written.
+func CopyTable(from *awssessions.SessionParams, to *awssessions.SessionParams, cp *CopyParams) (int64, error) {
+	return copyItems(dynamodb.New(awssessions.GetSession(from)), dynamodb.New(awssessions.GetSession(to)), cp)
+}
+
+func copyItems(src dynamodbiface.DynamoDBAPI, dst dynamodbiface.DynamoDBAPI, cp *CopyParams) (int64, error) {
+	desc := describe(src, cp.Table)
+	toDesc := describe(dst, cp.ToTable)
+	pages := selectPages(src, desc, cp.Table, cp.Index, cp.Query, cp.Filter, cp.Segments, nil)
+	state, err := loadCopyState(cp.State, cp.Table, cp.ToTable, len(pages))
+	if err != nil {
+		return 0, err
+	}
+	limiter := &rateLimiter{rate: cp.Rate}
+	return runPages(pages, cp.Segments, func(i int) (int64, error) {
+		if state.Segments[i].Done {
+			return 0, nil
+		}
+		w := newBatchWriter(dst, toDesc, cp.ToTable, limiter)
+		return copySegmentPages(pages[i], state, state.Segments[i], w, cp.Transform)
+	})
+}
+
+// copySegmentPages copies the pages of the segment, each page is fully written before the progress is saved.
+func copySegmentPages(
+	pages pagesFunc, state *copyState, segment *copySegment, w *batchWriter, transform *Transform) (int64, error) {
+
+	var err error
+	pagesErr := pages(segment.LastEvaluatedKey,
+		func(items []map[string]*dynamodb.AttributeValue, lastEvaluatedKey map[string]*dynamodb.AttributeValue) bool {
+			written := w.written
+			for _, item := range items {
+				if err = w.put(transform.apply(item)); err != nil {
+					return false
+				}
+			}
+			if err = w.flush(); err != nil {
+				return false
+			}
+			err = state.update(segment, lastEvaluatedKey, w.written-written)
+			return err == nil
+		})
+	if pagesErr != nil {
+		return w.written, pagesErr
+	}
+	return w.written, err
+}
@@ -0,0 +1,143 @@
+package dynamodb
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type copyDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	// failAfter fails the scan after the number of the pages, 0 means never
+	failAfter int
+}
+
+func (m copyDynamoDBClient) DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: ordersDescription}, nil
+}
+
+// ScanPages returns 6 orders in the pages of 2 items, starting after the exclusive start key.
+func (m copyDynamoDBClient) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	start := 1
+	if input.ExclusiveStartKey != nil {
+		last, _ := strconv.Atoi(aws.StringValue(input.ExclusiveStartKey["orderId"].N))
+		start = last + 1
+	}
+	for pages := 1; start <= 6; pages++ {
+		if m.failAfter != 0 && pages > m.failAfter {
+			return errors.New("scan failed")
+		}
+		page := &dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{
+			order("c1", strconv.Itoa(start)), order("c1", strconv.Itoa(start+1)),
+		}}
+		if start+1 < 6 {
+			page.LastEvaluatedKey = order("c1", strconv.Itoa(start+1))
+		}
+		start += 2
+		if !fn(page, page.LastEvaluatedKey == nil) {
+			return nil
+		}
+	}
+	return nil
+}
+
+type copyTargetDynamoDBClient struct {
+	batchWriteDynamoDBClient
+}
+
+func (m *copyTargetDynamoDBClient) DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: ordersDescription}, nil
+}
+
+func TestParseTransform(t *testing.T) {
+	got, err := ParseTransform([]string{"secret"}, []string{"customerId=clientId"}, []string{"env=dev", "copied=true"})
+	if err != nil {
+		t.Fatalf("ParseTransform() error = %v", err)
+	}
+	want := &Transform{
+		Drop:   []string{"secret"},
+		Rename: map[string]string{"customerId": "clientId"},
+		Set: map[string]*dynamodb.AttributeValue{
+			"env":    {S: aws.String("dev")},
+			"copied": {BOOL: aws.Bool(true)},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTransform() = %v, want %v", got, want)
+	}
+	for _, rename := range []string{"customerId", "=clientId", "customerId="} {
+		if _, err := ParseTransform(nil, []string{rename}, nil); err == nil {
+			t.Errorf("ParseTransform() rename %q error = nil, want error", rename)
+		}
+	}
+}
+
+func TestTransformApply(t *testing.T) {
+	transform := &Transform{
+		Drop:   []string{"secret"},
+		Rename: map[string]string{"customerId": "clientId"},
+		Set:    map[string]*dynamodb.AttributeValue{"env": {S: aws.String("dev")}},
+	}
+	got := transform.apply(map[string]*dynamodb.AttributeValue{
+		"customerId": {S: aws.String("c1")},
+		"secret":     {S: aws.String("s")},
+	})
+	want := map[string]*dynamodb.AttributeValue{
+		"clientId": {S: aws.String("c1")},
+		"env":      {S: aws.String("dev")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apply() = %v, want %v", got, want)
+	}
+}
+
+func TestCopyItemsResume(t *testing.T) {
+	batchWriteItemBackoff = time.Millisecond
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cp := &CopyParams{
+		Table:    "source",
+		Query:    &QueryParams{},
+		Segments: 1,
+		ToTable:  "orders",
+		State:    filepath.Join(dir, "copy.json"),
+	}
+	dst := &copyTargetDynamoDBClient{}
+	written, err := copyItems(copyDynamoDBClient{failAfter: 2}, dst, cp)
+	if err == nil {
+		t.Fatalf("copyItems() error = nil, want scan error")
+	}
+	if written != 4 {
+		t.Errorf("copyItems() = %d, want 4", written)
+	}
+	written, err = copyItems(copyDynamoDBClient{}, dst, cp)
+	if err != nil {
+		t.Fatalf("copyItems() resumed error = %v", err)
+	}
+	if written != 2 {
+		t.Errorf("copyItems() resumed = %d, want 2", written)
+	}
+	// each item is written exactly once across both runs
+	copied := make(map[string]bool, len(dst.items))
+	for _, item := range dst.items {
+		copied[aws.StringValue(item["orderId"].N)] = true
+	}
+	if len(dst.items) != 6 || len(copied) != 6 {
+		t.Errorf("copyItems() items = %v, want orders 1 to 6 once", dst.items)
+	}
+	written, err = copyItems(copyDynamoDBClient{}, dst, cp)
+	if err != nil || written != 0 {
+		t.Errorf("copyItems() done = %d, %v, want 0, nil", written, err)
+	}
+}
@@ -0,0 +1,137 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"sync"
+)
+
+// Count represents the number of the items matched, and the number of the items evaluated before the filter is
+// applied, which are the same if there is no filter.
+type Count struct {
+	Count        int64 `json:"count"`
+	ScannedCount int64 `json:"scannedCount"`
+}
+
+// CountItems counts the items of the query or scan from the table (or index) using Select COUNT, so no item data is
+// transferred. The scan is split into the number of segments run in parallel.
+func CountItems(
+	sp *awssessions.SessionParams,
+	table string,
+	index string,
+	qp *QueryParams,
+	filter Filter,
+	segments uint) (*Count, error) {
+
+	return countItems(dynamodb.New(awssessions.GetSession(sp)), table, index, qp, filter, segments)
+}
+
+func countItems(
+	svc dynamodbiface.DynamoDBAPI,
+	table string,
+	index string,
+	qp *QueryParams,
+	filter Filter,
+	segments uint) (*Count, error) {
+
+	var desc *dynamodb.TableDescription
+	if !qp.isEmpty() || len(filter) != 0 {
+		desc = describe(svc, table)
+	}
+	if qp.isEmpty() {
+		return countScan(svc, desc, table, index, filter, segments)
+	}
+	if len(qp.Hashes) == 0 {
+		return countQuery(svc, desc, table, index, qp, filter)
+	}
+	total := &Count{}
+	for _, hash := range qp.Hashes {
+		count, err := countQuery(svc, desc, table, index, qp.withHash(hash), filter)
+		if err != nil {
+			return nil, err
+		}
+		total.Count += count.Count
+		total.ScannedCount += count.ScannedCount
+	}
+	return total, nil
+}
+
+func countQuery(
+	svc dynamodbiface.DynamoDBAPI,
+	desc *dynamodb.TableDescription,
+	table string,
+	index string,
+	qp *QueryParams,
+	filter Filter) (*Count, error) {
+
+	query := queryInput(desc, table, index, qp, filter)
+	query.Select = aws.String(dynamodb.SelectCount)
+	count := &Count{}
+	err := svc.QueryPages(query,
+		func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			count.Count += aws.Int64Value(page.Count)
+			count.ScannedCount += aws.Int64Value(page.ScannedCount)
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	return count, nil
+}
+
+func countScan(
+	svc dynamodbiface.DynamoDBAPI,
+	desc *dynamodb.TableDescription,
+	table string,
+	index string,
+	filter Filter,
+	segments uint) (*Count, error) {
+
+	if segments == 0 {
+		segments = 1
+	}
+	counts := make([]Count, segments)
+	errs := make([]error, segments)
+	var wg sync.WaitGroup
+	for segment := uint(0); segment < segments; segment++ {
+		scan := dynamodb.ScanInput{
+			TableName: aws.String(table),
+			Select:    aws.String(dynamodb.SelectCount),
+		}
+		if index != "" {
+			scan.IndexName = aws.String(index)
+		}
+		if segments > 1 {
+			scan.Segment = aws.Int64(int64(segment))
+			scan.TotalSegments = aws.Int64(int64(segments))
+		}
+		if len(filter) != 0 {
+			expr := filter.filterExpression(desc.AttributeDefinitions)
+			scan.FilterExpression = expr.Filter()
+			scan.ExpressionAttributeNames = expr.Names()
+			scan.ExpressionAttributeValues = expr.Values()
+		}
+		wg.Add(1)
+		go func(segment uint, scan *dynamodb.ScanInput) {
+			defer wg.Done()
+			errs[segment] = svc.ScanPages(scan,
+				func(page *dynamodb.ScanOutput, lastPage bool) bool {
+					counts[segment].Count += aws.Int64Value(page.Count)
+					counts[segment].ScannedCount += aws.Int64Value(page.ScannedCount)
+					return true
+				})
+		}(segment, &scan)
+	}
+	wg.Wait()
+	count := &Count{}
+	for segment := range counts {
+		if errs[segment] != nil {
+			return nil, errs[segment]
+		}
+		count.Count += counts[segment].Count
+		count.ScannedCount += counts[segment].ScannedCount
+	}
+	return count, nil
+}
@@ -0,0 +1,90 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type countDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	mu    sync.Mutex
+	scans []*dynamodb.ScanInput
+	query *dynamodb.QueryInput
+}
+
+func (m *countDynamoDBClient) DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: ordersDescription}, nil
+}
+
+func (m *countDynamoDBClient) ScanPages(
+	input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	m.mu.Lock()
+	m.scans = append(m.scans, input)
+	m.mu.Unlock()
+	// each segment has 2 pages, with the segment's number of items matched out of 10 scanned per page
+	segment := aws.Int64Value(input.Segment)
+	if fn(&dynamodb.ScanOutput{Count: aws.Int64(segment), ScannedCount: aws.Int64(10)}, false) {
+		fn(&dynamodb.ScanOutput{Count: aws.Int64(segment), ScannedCount: aws.Int64(10)}, true)
+	}
+	return nil
+}
+
+func (m *countDynamoDBClient) QueryPages(
+	input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	m.query = input
+	if fn(&dynamodb.QueryOutput{Count: aws.Int64(3), ScannedCount: aws.Int64(5)}, false) {
+		fn(&dynamodb.QueryOutput{Count: aws.Int64(1), ScannedCount: aws.Int64(1)}, true)
+	}
+	return nil
+}
+
+func TestCountItemsScan(t *testing.T) {
+	svc := &countDynamoDBClient{}
+	filter := Filter{{Attribute: "total", Operator: filterGreaterThan, Value: "100"}}
+	got, err := countItems(svc, "orders", "byStatus", &QueryParams{}, filter, 4)
+	if err != nil {
+		t.Fatalf("countItems() error = %v", err)
+	}
+	// segments 0..3 match 0+1+2+3 items per page
+	if want := (&Count{Count: 12, ScannedCount: 80}); !reflect.DeepEqual(got, want) {
+		t.Errorf("countItems() = %v, want %v", got, want)
+	}
+	if len(svc.scans) != 4 {
+		t.Fatalf("countItems() scans = %d, want %d", len(svc.scans), 4)
+	}
+	segments := make(map[int64]bool)
+	for _, scan := range svc.scans {
+		if aws.StringValue(scan.Select) != dynamodb.SelectCount ||
+			aws.Int64Value(scan.TotalSegments) != 4 ||
+			aws.StringValue(scan.IndexName) != "byStatus" ||
+			aws.StringValue(scan.FilterExpression) != "#0 > :0" {
+			t.Errorf("countItems() scan = %v", scan)
+		}
+		segments[aws.Int64Value(scan.Segment)] = true
+	}
+	if len(segments) != 4 {
+		t.Errorf("countItems() segments = %v, want 4 distinct", segments)
+	}
+}
+
+func TestCountItemsQuery(t *testing.T) {
+	svc := &countDynamoDBClient{}
+	got, err := countItems(svc, "orders", "byStatusLocal", &QueryParams{Hash: "c1", SortBeginsWith: "A"}, nil, 4)
+	if err != nil {
+		t.Fatalf("countItems() error = %v", err)
+	}
+	if want := (&Count{Count: 4, ScannedCount: 6}); !reflect.DeepEqual(got, want) {
+		t.Errorf("countItems() = %v, want %v", got, want)
+	}
+	if aws.StringValue(svc.query.Select) != dynamodb.SelectCount ||
+		aws.StringValue(svc.query.IndexName) != "byStatusLocal" ||
+		aws.StringValue(svc.query.KeyConditionExpression) != "(#0 = :0) AND (begins_with (#1, :1))" ||
+		aws.StringValue(svc.query.ExpressionAttributeNames["#1"]) != "status" ||
+		svc.query.FilterExpression != nil {
+		t.Errorf("countItems() query = %v", svc.query)
+	}
+}
@@ -0,0 +1,170 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"github.com/zshamrock/dynocsv/output"
+	"sync"
+)
+
+// DeleteParams represents the items to delete from the table: the ones of the keys if Query.Keys is set, or of the
+// query, or of the scan split into the number of segments run in parallel if the query is empty, matching the filter.
+// At most Rate items are deleted per second (0 means no limit). If Backup is set, the items are written into it before
+// they are deleted.
+type DeleteParams struct {
+	Table    string
+	Index    string
+	Query    *QueryParams
+	Filter   Filter
+	Segments uint
+	Rate     float64
+	Backup   output.Writer
+}
+
+// backupWriter writes the items into the backup as CSV before they are deleted, it is safe to share it between the
+// segments running concurrently.
+type backupWriter struct {
+	mu     sync.Mutex
+	writer output.Writer
+	e      *exporter
+}
+
+func newBackupWriter(desc *dynamodb.TableDescription, writer output.Writer) *backupWriter {
+	attributes := keyAttributeNames(desc)
+	attributesSet := make(map[string]bool, len(attributes))
+	for _, attribute := range attributes {
+		attributesSet[attribute] = true
+	}
+	return &backupWriter{
+		writer: writer,
+		e: &exporter{
+			skipAttributes: make(map[string]bool),
+			attributes:     attributes,
+			attributesSet:  attributesSet,
+			keyAttributes:  attributes,
+			writers:        SingleWriter(writer),
+			sinks:          make(map[string]*sink),
+		},
+	}
+}
+
+// write writes and flushes the items, so they are in the backup before they are deleted.
+func (b *backupWriter) write(items []map[string]*dynamodb.AttributeValue) error {
+	if b == nil || len(items) == 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.e.process(items, false)
+	b.e.flush(true)
+	return b.writer.Flush()
+}
+
+// DeleteItems deletes the items using BatchWriteItem, and returns the number of the items deleted. Unless the backup is
+// set, only the key attributes of the items are read.
+func DeleteItems(sp *awssessions.SessionParams, dp *DeleteParams) (int64, error) {
+	return deleteItems(dynamodb.New(awssessions.GetSession(sp)), dp)
+}
+
+func deleteItems(svc dynamodbiface.DynamoDBAPI, dp *DeleteParams) (int64, error) {
+	desc := describe(svc, dp.Table)
+	limiter := &rateLimiter{rate: dp.Rate}
+	var backup *backupWriter
+	if dp.Backup != nil {
+		backup = newBackupWriter(desc, dp.Backup)
+	}
+	if dp.Query.Keys != nil {
+		return deleteKeys(svc, desc, dp, backup, limiter)
+	}
+	keyAttributes := keyAttributeNames(desc)
+	var projection []string
+	if backup == nil {
+		projection = keyAttributes
+	}
+	pages := selectPages(svc, desc, dp.Table, dp.Index, dp.Query, dp.Filter, dp.Segments, projection)
+	return runPages(pages, dp.Segments, func(i int) (int64, error) {
+		w := newBatchWriter(svc, desc, dp.Table, limiter)
+		var err error
+		pagesErr := pages[i](nil,
+			func(items []map[string]*dynamodb.AttributeValue, _ map[string]*dynamodb.AttributeValue) bool {
+				if err = backup.write(items); err != nil {
+					return false
+				}
+				for _, item := range items {
+					key := make(map[string]*dynamodb.AttributeValue, len(keyAttributes))
+					for _, attribute := range keyAttributes {
+						key[attribute] = item[attribute]
+					}
+					if err = w.delete(key); err != nil {
+						return false
+					}
+				}
+				err = w.flush()
+				return err == nil
+			})
+		if pagesErr != nil {
+			return w.written, pagesErr
+		}
+		return w.written, err
+	})
+}
+
+// deleteKeys deletes the items by the keys in the chunks of 100 keys, the found items of each chunk are fetched with
+// BatchGetItem and written into the backup first if it is set. The duplicate keys are deleted only once.
+func deleteKeys(
+	svc dynamodbiface.DynamoDBAPI,
+	desc *dynamodb.TableDescription,
+	dp *DeleteParams,
+	backup *backupWriter,
+	limiter *rateLimiter) (int64, error) {
+
+	keys := dp.Query.Keys
+	if err := keys.validate(desc); err != nil {
+		return 0, err
+	}
+	definitions := definitionsMapping(desc.AttributeDefinitions)
+	w := newBatchWriter(svc, desc, dp.Table, limiter)
+	seen := make(map[string]bool, len(keys.Values))
+	for start := 0; start < len(keys.Values); start += batchGetItemLimit {
+		end := start + batchGetItemLimit
+		if end > len(keys.Values) {
+			end = len(keys.Values)
+		}
+		chunk := make([]map[string]*dynamodb.AttributeValue, 0, end-start)
+		for _, values := range keys.Values[start:end] {
+			key, err := keys.attributeValues(values, definitions)
+			if err != nil {
+				return w.written, err
+			}
+			id := keyID(key, keys.Attributes)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			chunk = append(chunk, key)
+		}
+		if backup != nil && len(chunk) != 0 {
+			fetched, err := batchGetItems(svc, dp.Table, chunk, keys.Attributes)
+			if err != nil {
+				return w.written, err
+			}
+			items := make([]map[string]*dynamodb.AttributeValue, 0, len(fetched))
+			for _, key := range chunk {
+				if item, ok := fetched[keyID(key, keys.Attributes)]; ok {
+					items = append(items, item)
+				}
+			}
+			if err := backup.write(items); err != nil {
+				return w.written, err
+			}
+		}
+		for _, key := range chunk {
+			if err := w.delete(key); err != nil {
+				return w.written, err
+			}
+		}
+	}
+	err := w.flush()
+	return w.written, err
+}
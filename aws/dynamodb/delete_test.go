@@ -0,0 +1,129 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type deleteDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	mu      sync.Mutex
+	scans   []*dynamodb.ScanInput
+	deleted []map[string]*dynamodb.AttributeValue
+}
+
+func (m *deleteDynamoDBClient) DescribeTable(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: ordersDescription}, nil
+}
+
+// ScanPages returns the single page with the orders 1 and 2 of the customer c1.
+func (m *deleteDynamoDBClient) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	m.mu.Lock()
+	m.scans = append(m.scans, input)
+	m.mu.Unlock()
+	items := []map[string]*dynamodb.AttributeValue{order("c1", "1"), order("c1", "2")}
+	if input.ProjectionExpression == nil {
+		for _, item := range items {
+			item["status"] = &dynamodb.AttributeValue{S: aws.String("NEW")}
+		}
+	}
+	fn(&dynamodb.ScanOutput{Items: items}, true)
+	return nil
+}
+
+// BatchGetItem returns all the requested orders except the order 2.
+func (m *deleteDynamoDBClient) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	items := make([]map[string]*dynamodb.AttributeValue, 0)
+	for _, key := range input.RequestItems["orders"].Keys {
+		if aws.StringValue(key["orderId"].N) != "2" {
+			items = append(items, key)
+		}
+	}
+	return &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]*dynamodb.AttributeValue{"orders": items}}, nil
+}
+
+func (m *deleteDynamoDBClient) BatchWriteItem(
+	input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, request := range input.RequestItems["orders"] {
+		m.deleted = append(m.deleted, request.DeleteRequest.Key)
+	}
+	return &dynamodb.BatchWriteItemOutput{}, nil
+}
+
+func TestDeleteItemsScan(t *testing.T) {
+	svc := &deleteDynamoDBClient{}
+	deleted, err := deleteItems(svc, &DeleteParams{Table: "orders", Query: &QueryParams{}, Segments: 2})
+	if err != nil {
+		t.Fatalf("deleteItems() error = %v", err)
+	}
+	if deleted != 4 {
+		t.Errorf("deleteItems() = %d, want 4", deleted)
+	}
+	for _, scan := range svc.scans {
+		if got := aws.StringValue(scan.ProjectionExpression); got != "#p0, #p1" {
+			t.Errorf("ProjectionExpression = %v, want #p0, #p1", got)
+		}
+		names := map[string]*string{"#p0": aws.String("customerId"), "#p1": aws.String("orderId")}
+		if !reflect.DeepEqual(scan.ExpressionAttributeNames, names) {
+			t.Errorf("ExpressionAttributeNames = %v, want %v", scan.ExpressionAttributeNames, names)
+		}
+	}
+	for _, key := range svc.deleted {
+		if len(key) != 2 {
+			t.Errorf("deleted key = %v, want key attributes only", key)
+		}
+	}
+}
+
+func TestDeleteItemsBackup(t *testing.T) {
+	svc := &deleteDynamoDBClient{}
+	backup := &recordingWriter{}
+	deleted, err := deleteItems(svc, &DeleteParams{Table: "orders", Query: &QueryParams{}, Backup: backup})
+	if err != nil {
+		t.Fatalf("deleteItems() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleteItems() = %d, want 2", deleted)
+	}
+	if svc.scans[0].ProjectionExpression != nil {
+		t.Errorf("ProjectionExpression = %v, want full items for backup", aws.StringValue(svc.scans[0].ProjectionExpression))
+	}
+	if want := []string{"customerId", "orderId", "status"}; !reflect.DeepEqual(backup.header, want) {
+		t.Errorf("backup header = %v, want %v", backup.header, want)
+	}
+	if want := [][]string{{"c1", "1", "NEW"}, {"c1", "2", "NEW"}}; !reflect.DeepEqual(backup.records, want) {
+		t.Errorf("backup records = %v, want %v", backup.records, want)
+	}
+}
+
+func TestDeleteItemsKeys(t *testing.T) {
+	batchGetItemBackoff = time.Millisecond
+	svc := &deleteDynamoDBClient{}
+	backup := &recordingWriter{}
+	keys := &Keys{
+		Attributes: []string{"orderId", "customerId"},
+		Values:     [][]string{{"1", "c1"}, {"2", "c1"}, {"1.0", "c1"}},
+	}
+	deleted, err := deleteItems(svc, &DeleteParams{Table: "orders", Query: &QueryParams{Keys: keys}, Backup: backup})
+	if err != nil {
+		t.Fatalf("deleteItems() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleteItems() = %d, want 2", deleted)
+	}
+	want := []map[string]*dynamodb.AttributeValue{order("c1", "1"), order("c1", "2")}
+	if !reflect.DeepEqual(svc.deleted, want) {
+		t.Errorf("deleted = %v, want %v", svc.deleted, want)
+	}
+	if want := [][]string{{"c1", "1"}}; !reflect.DeepEqual(backup.records, want) {
+		t.Errorf("backup records = %v, want %v", backup.records, want)
+	}
+}
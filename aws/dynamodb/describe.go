@@ -0,0 +1,240 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// TableSchema represents the table's schema and settings, as well as its current item count and size.
+type TableSchema struct {
+	Name                   string         `json:"name"`
+	Status                 string         `json:"status,omitempty"`
+	KeySchema              []KeyAttribute `json:"keySchema"`
+	GlobalSecondaryIndexes []IndexSchema  `json:"globalSecondaryIndexes,omitempty"`
+	LocalSecondaryIndexes  []IndexSchema  `json:"localSecondaryIndexes,omitempty"`
+	ItemCount              int64          `json:"itemCount"`
+	SizeBytes              int64          `json:"sizeBytes"`
+	BillingMode            string         `json:"billingMode"`
+	Throughput             *Throughput    `json:"provisionedThroughput,omitempty"`
+	Stream                 *StreamSchema  `json:"stream,omitempty"`
+	TTL                    *TTLSchema     `json:"ttl,omitempty"`
+}
+
+// KeyAttribute represents the key attribute with its key type (HASH or RANGE) and attribute type (S, N or B).
+type KeyAttribute struct {
+	Name          string `json:"name"`
+	KeyType       string `json:"keyType"`
+	AttributeType string `json:"attributeType"`
+}
+
+// IndexSchema represents the global or local secondary index.
+type IndexSchema struct {
+	Name       string           `json:"name"`
+	Status     string           `json:"status,omitempty"`
+	KeySchema  []KeyAttribute   `json:"keySchema"`
+	Projection ProjectionSchema `json:"projection"`
+	Throughput *Throughput      `json:"provisionedThroughput,omitempty"`
+	ItemCount  int64            `json:"itemCount"`
+	SizeBytes  int64            `json:"sizeBytes"`
+}
+
+// ProjectionSchema represents the attributes projected into the index.
+type ProjectionSchema struct {
+	Type             string   `json:"type"`
+	NonKeyAttributes []string `json:"nonKeyAttributes,omitempty"`
+}
+
+// Throughput represents the provisioned read and write capacity units.
+type Throughput struct {
+	ReadCapacityUnits  int64 `json:"readCapacityUnits"`
+	WriteCapacityUnits int64 `json:"writeCapacityUnits"`
+}
+
+// StreamSchema represents the table's stream settings.
+type StreamSchema struct {
+	Enabled  bool   `json:"enabled"`
+	ViewType string `json:"viewType,omitempty"`
+	ARN      string `json:"arn,omitempty"`
+}
+
+// TTLSchema represents the table's time to live settings.
+type TTLSchema struct {
+	Status    string `json:"status"`
+	Attribute string `json:"attribute,omitempty"`
+}
+
+// DescribeTable returns the schema and settings of the table.
+func DescribeTable(sp *awssessions.SessionParams, table string) (*TableSchema, error) {
+	return describeTable(dynamodb.New(awssessions.GetSession(sp)), table)
+}
+
+func describeTable(svc dynamodbiface.DynamoDBAPI, table string) (*TableSchema, error) {
+	output, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching table %s description %v", table, err)
+	}
+	ttl, err := svc.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching table %s time to live description %v", table, err)
+	}
+	return buildTableSchema(output.Table, ttl.TimeToLiveDescription), nil
+}
+
+func buildTableSchema(desc *dynamodb.TableDescription, ttl *dynamodb.TimeToLiveDescription) *TableSchema {
+	definitions := make(map[string]string)
+	for _, definition := range desc.AttributeDefinitions {
+		definitions[aws.StringValue(definition.AttributeName)] = aws.StringValue(definition.AttributeType)
+	}
+	schema := &TableSchema{
+		Name:        aws.StringValue(desc.TableName),
+		Status:      aws.StringValue(desc.TableStatus),
+		KeySchema:   buildKeyAttributes(desc.KeySchema, definitions),
+		ItemCount:   aws.Int64Value(desc.ItemCount),
+		SizeBytes:   aws.Int64Value(desc.TableSizeBytes),
+		BillingMode: dynamodb.BillingModeProvisioned,
+	}
+	if desc.BillingModeSummary != nil && desc.BillingModeSummary.BillingMode != nil {
+		schema.BillingMode = aws.StringValue(desc.BillingModeSummary.BillingMode)
+	}
+	if schema.BillingMode == dynamodb.BillingModeProvisioned {
+		schema.Throughput = buildThroughput(desc.ProvisionedThroughput)
+	}
+	for _, index := range desc.GlobalSecondaryIndexes {
+		is := IndexSchema{
+			Name:       aws.StringValue(index.IndexName),
+			Status:     aws.StringValue(index.IndexStatus),
+			KeySchema:  buildKeyAttributes(index.KeySchema, definitions),
+			Projection: buildProjection(index.Projection),
+			ItemCount:  aws.Int64Value(index.ItemCount),
+			SizeBytes:  aws.Int64Value(index.IndexSizeBytes),
+		}
+		if schema.BillingMode == dynamodb.BillingModeProvisioned {
+			is.Throughput = buildThroughput(index.ProvisionedThroughput)
+		}
+		schema.GlobalSecondaryIndexes = append(schema.GlobalSecondaryIndexes, is)
+	}
+	for _, index := range desc.LocalSecondaryIndexes {
+		schema.LocalSecondaryIndexes = append(schema.LocalSecondaryIndexes, IndexSchema{
+			Name:       aws.StringValue(index.IndexName),
+			KeySchema:  buildKeyAttributes(index.KeySchema, definitions),
+			Projection: buildProjection(index.Projection),
+			ItemCount:  aws.Int64Value(index.ItemCount),
+			SizeBytes:  aws.Int64Value(index.IndexSizeBytes),
+		})
+	}
+	if spec := desc.StreamSpecification; spec != nil {
+		schema.Stream = &StreamSchema{
+			Enabled:  aws.BoolValue(spec.StreamEnabled),
+			ViewType: aws.StringValue(spec.StreamViewType),
+			ARN:      aws.StringValue(desc.LatestStreamArn),
+		}
+	}
+	if ttl != nil {
+		schema.TTL = &TTLSchema{
+			Status:    aws.StringValue(ttl.TimeToLiveStatus),
+			Attribute: aws.StringValue(ttl.AttributeName),
+		}
+	}
+	return schema
+}
+
+func buildKeyAttributes(keys []*dynamodb.KeySchemaElement, definitions map[string]string) []KeyAttribute {
+	attributes := make([]KeyAttribute, 0, len(keys))
+	for _, key := range []*dynamodb.KeySchemaElement{findHashKey(keys), findRangeKey(keys)} {
+		if key == nil {
+			continue
+		}
+		name := aws.StringValue(key.AttributeName)
+		attributes = append(attributes, KeyAttribute{
+			Name:          name,
+			KeyType:       aws.StringValue(key.KeyType),
+			AttributeType: definitions[name],
+		})
+	}
+	return attributes
+}
+
+func buildProjection(projection *dynamodb.Projection) ProjectionSchema {
+	if projection == nil {
+		return ProjectionSchema{Type: dynamodb.ProjectionTypeAll}
+	}
+	return ProjectionSchema{
+		Type:             aws.StringValue(projection.ProjectionType),
+		NonKeyAttributes: aws.StringValueSlice(projection.NonKeyAttributes),
+	}
+}
+
+func buildThroughput(throughput *dynamodb.ProvisionedThroughputDescription) *Throughput {
+	if throughput == nil {
+		return nil
+	}
+	return &Throughput{
+		ReadCapacityUnits:  aws.Int64Value(throughput.ReadCapacityUnits),
+		WriteCapacityUnits: aws.Int64Value(throughput.WriteCapacityUnits),
+	}
+}
+
+// Print prints the human readable table schema.
+func (s *TableSchema) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Table:\t%s\n", s.Name)
+	fmt.Fprintf(tw, "Status:\t%s\n", s.Status)
+	fmt.Fprintf(tw, "Item count:\t%d\n", s.ItemCount)
+	fmt.Fprintf(tw, "Size:\t%d bytes\n", s.SizeBytes)
+	billingMode := s.BillingMode
+	if s.Throughput != nil {
+		billingMode = fmt.Sprintf("%s (%d RCU, %d WCU)",
+			billingMode, s.Throughput.ReadCapacityUnits, s.Throughput.WriteCapacityUnits)
+	}
+	fmt.Fprintf(tw, "Billing mode:\t%s\n", billingMode)
+	stream := "DISABLED"
+	if s.Stream != nil && s.Stream.Enabled {
+		stream = fmt.Sprintf("%s (%s)", s.Stream.ViewType, s.Stream.ARN)
+	}
+	fmt.Fprintf(tw, "Stream:\t%s\n", stream)
+	ttl := "DISABLED"
+	if s.TTL != nil {
+		ttl = s.TTL.Status
+		if s.TTL.Attribute != "" {
+			ttl = fmt.Sprintf("%s (%s)", ttl, s.TTL.Attribute)
+		}
+	}
+	fmt.Fprintf(tw, "TTL:\t%s\n", ttl)
+	fmt.Fprintln(tw, "\nKey schema:")
+	printKeyAttributes(tw, "  ", s.KeySchema)
+	printIndexes(tw, "Global secondary indexes:", s.GlobalSecondaryIndexes)
+	printIndexes(tw, "Local secondary indexes:", s.LocalSecondaryIndexes)
+	return tw.Flush()
+}
+
+func printKeyAttributes(w io.Writer, indent string, keys []KeyAttribute) {
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s\t%s\t%s\n", indent, key.Name, key.KeyType, key.AttributeType)
+	}
+}
+
+func printIndexes(w io.Writer, title string, indexes []IndexSchema) {
+	if len(indexes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", title)
+	for _, index := range indexes {
+		status := ""
+		if index.Status != "" {
+			status = index.Status + ", "
+		}
+		fmt.Fprintf(w, "  %s (%s%d items, %d bytes)\n", index.Name, status, index.ItemCount, index.SizeBytes)
+		printKeyAttributes(w, "    ", index.KeySchema)
+		projection := index.Projection.Type
+		if len(index.Projection.NonKeyAttributes) != 0 {
+			projection = fmt.Sprintf("%s [%s]", projection, strings.Join(index.Projection.NonKeyAttributes, ","))
+		}
+		fmt.Fprintf(w, "    projection:\t%s\n", projection)
+	}
+}
@@ -0,0 +1,157 @@
+package dynamodb
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+var ordersDescription = &dynamodb.TableDescription{
+	TableName:      aws.String("orders"),
+	TableStatus:    aws.String(dynamodb.TableStatusActive),
+	ItemCount:      aws.Int64(10),
+	TableSizeBytes: aws.Int64(1024),
+	AttributeDefinitions: []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String("customerId"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		{AttributeName: aws.String("orderId"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+		{AttributeName: aws.String("status"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+	},
+	KeySchema: []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String("orderId"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		{AttributeName: aws.String("customerId"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+	},
+	BillingModeSummary: &dynamodb.BillingModeSummary{BillingMode: aws.String(dynamodb.BillingModePayPerRequest)},
+	GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+		{
+			IndexName:   aws.String("byStatus"),
+			IndexStatus: aws.String(dynamodb.IndexStatusActive),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("status"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			},
+			Projection: &dynamodb.Projection{
+				ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+				NonKeyAttributes: []*string{aws.String("total")},
+			},
+			ItemCount:      aws.Int64(5),
+			IndexSizeBytes: aws.Int64(512),
+		},
+	},
+	LocalSecondaryIndexes: []*dynamodb.LocalSecondaryIndexDescription{
+		{
+			IndexName: aws.String("byStatusLocal"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("customerId"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				{AttributeName: aws.String("status"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly)},
+		},
+	},
+	StreamSpecification: &dynamodb.StreamSpecification{
+		StreamEnabled:  aws.Bool(true),
+		StreamViewType: aws.String(dynamodb.StreamViewTypeNewAndOldImages),
+	},
+	LatestStreamArn: aws.String("arn:stream"),
+}
+
+var ordersSchema = &TableSchema{
+	Name:   "orders",
+	Status: dynamodb.TableStatusActive,
+	KeySchema: []KeyAttribute{
+		{Name: "customerId", KeyType: dynamodb.KeyTypeHash, AttributeType: dynamodb.ScalarAttributeTypeS},
+		{Name: "orderId", KeyType: dynamodb.KeyTypeRange, AttributeType: dynamodb.ScalarAttributeTypeN},
+	},
+	GlobalSecondaryIndexes: []IndexSchema{
+		{
+			Name:   "byStatus",
+			Status: dynamodb.IndexStatusActive,
+			KeySchema: []KeyAttribute{
+				{Name: "status", KeyType: dynamodb.KeyTypeHash, AttributeType: dynamodb.ScalarAttributeTypeS},
+			},
+			Projection: ProjectionSchema{Type: dynamodb.ProjectionTypeInclude, NonKeyAttributes: []string{"total"}},
+			ItemCount:  5,
+			SizeBytes:  512,
+		},
+	},
+	LocalSecondaryIndexes: []IndexSchema{
+		{
+			Name: "byStatusLocal",
+			KeySchema: []KeyAttribute{
+				{Name: "customerId", KeyType: dynamodb.KeyTypeHash, AttributeType: dynamodb.ScalarAttributeTypeS},
+				{Name: "status", KeyType: dynamodb.KeyTypeRange, AttributeType: dynamodb.ScalarAttributeTypeS},
+			},
+			Projection: ProjectionSchema{Type: dynamodb.ProjectionTypeKeysOnly, NonKeyAttributes: []string{}},
+		},
+	},
+	ItemCount:   10,
+	SizeBytes:   1024,
+	BillingMode: dynamodb.BillingModePayPerRequest,
+	Stream:      &StreamSchema{Enabled: true, ViewType: dynamodb.StreamViewTypeNewAndOldImages, ARN: "arn:stream"},
+	TTL:         &TTLSchema{Status: dynamodb.TimeToLiveStatusEnabled, Attribute: "expiresAt"},
+}
+
+func TestBuildTableSchema(t *testing.T) {
+	got := buildTableSchema(ordersDescription, &dynamodb.TimeToLiveDescription{
+		TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled),
+		AttributeName:    aws.String("expiresAt"),
+	})
+	if !reflect.DeepEqual(got, ordersSchema) {
+		t.Errorf("buildTableSchema() = %+v, want %+v", got, ordersSchema)
+	}
+}
+
+func TestBuildTableSchemaProvisioned(t *testing.T) {
+	got := buildTableSchema(&dynamodb.TableDescription{
+		TableName: aws.String("t1"),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("Id"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(1),
+		},
+	}, nil)
+	if got.BillingMode != dynamodb.BillingModeProvisioned {
+		t.Errorf("BillingMode = %v, want %v", got.BillingMode, dynamodb.BillingModeProvisioned)
+	}
+	if want := (&Throughput{ReadCapacityUnits: 5, WriteCapacityUnits: 1}); !reflect.DeepEqual(got.Throughput, want) {
+		t.Errorf("Throughput = %v, want %v", got.Throughput, want)
+	}
+	if got.Stream != nil || got.TTL != nil {
+		t.Errorf("Stream = %v, TTL = %v, want both not set", got.Stream, got.TTL)
+	}
+}
+
+func TestTableSchemaPrint(t *testing.T) {
+	var b bytes.Buffer
+	if err := ordersSchema.Print(&b); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	want := `Table:         orders
+Status:        ACTIVE
+Item count:    10
+Size:          1024 bytes
+Billing mode:  PAY_PER_REQUEST
+Stream:        NEW_AND_OLD_IMAGES (arn:stream)
+TTL:           ENABLED (expiresAt)
+
+Key schema:
+  customerId  HASH   S
+  orderId     RANGE  N
+
+Global secondary indexes:
+  byStatus (ACTIVE, 5 items, 512 bytes)
+    status       HASH  S
+    projection:  INCLUDE [total]
+
+Local secondary indexes:
+  byStatusLocal (0 items, 0 bytes)
+    customerId   HASH   S
+    status       RANGE  S
+    projection:  KEYS_ONLY
+`
+	if got := b.String(); got != want {
+		t.Errorf("Print() = %v, want %v", got, want)
+	}
+}
@@ -1,7 +1,6 @@
 package dynamodb
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
@@ -9,11 +8,12 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	awssessions "github.com/zshamrock/dynocsv/aws"
-	"io"
+	"github.com/zshamrock/dynocsv/output"
 	"log"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -26,9 +26,16 @@ const (
 	listCloseSymbol     = "]"
 )
 
-// QueryParams represents the query params set by the user, either hash or hash and sort.
+// QueryParams represents the query params set by the user, either hash or hash and sort. If Hashes are set, the query
+// is run for each of them instead of Hash with the same sort condition. If Keys are set, the items are fetched by their
+// full primary keys instead, or if Statement is set, the items are selected by the PartiQL statement. Desc returns the
+// items of the query in the descending order of the sort key.
 type QueryParams struct {
+	Desc           bool
+	Keys           *Keys
+	Statement      *Statement
 	Hash           string
+	Hashes         []string
 	Sort           string
 	SortGt         string
 	SortGe         string
@@ -36,6 +43,12 @@ type QueryParams struct {
 	SortLe         string
 	SortBeginsWith string
 	SortBetween    []string
+	// Since and Until are the time range of the sort key, translated into the sort condition using SortFormat
+	Since      *time.Time
+	Until      *time.Time
+	SortFormat string
+	// Incremental selects only the items changed since the last run of the incremental export
+	Incremental *Incremental
 }
 
 type writerBuffer struct {
@@ -44,10 +57,13 @@ type writerBuffer struct {
 	buffer  [][]string
 }
 
-func (wb *writerBuffer) flush(writer *csv.Writer, attributes []string) {
-	_ = writer.Write(attributes)
+func (wb *writerBuffer) flush(writer output.Writer, attributes []string) {
+	_ = writer.WriteHeader(attributes)
 	if wb.flushed {
-		_ = writer.WriteAll(wb.buffer)
+		for _, records := range wb.buffer {
+			_ = writer.Write(records)
+		}
+		_ = writer.Flush()
 	} else {
 		// If buffer has not been flushed, the previous stored records might not be in sync with the latest attributes
 		// count, i.e. extra blank values have to be appended to each of such records
@@ -57,13 +73,66 @@ func (wb *writerBuffer) flush(writer *csv.Writer, attributes []string) {
 			}
 			_ = writer.Write(records)
 		}
+		_ = writer.Flush()
 	}
 	wb.flushed = true
 	wb.buffer = nil
 }
 
-var wb = &writerBuffer{flushed: false, limit: 1000, buffer: make([][]string, 0, 100)}
-var forceAttributesStdout = false
+const writerBufferLimit = 1000
+
+// sink is the single output with its own set of the detected attributes (i.e. CSV headers), and the buffer of the
+// records kept in memory until the attributes are considered to be settled.
+type sink struct {
+	writer        output.Writer
+	wb            *writerBuffer
+	attributes    []string
+	attributesSet map[string]bool
+	// set if the new attributes have been detected after the CSV headers have been already written
+	forceAttributesStdout bool
+}
+
+// exporter routes the fetched items into the corresponding sinks, each sink starts with the same baseline attributes.
+type exporter struct {
+	columns        string
+	skipAttributes map[string]bool
+	attributes     []string
+	attributesSet  map[string]bool
+	keyAttributes  []string
+	limit          uint
+	processed      int
+	writers        Writers
+	sinks          map[string]*sink
+	keys           []string
+	incremental    *Incremental
+}
+
+func (e *exporter) sink(key string) *sink {
+	if s, ok := e.sinks[key]; ok {
+		return s
+	}
+	attributes := e.attributes
+	if e.writers.KeysOnly() && e.columns == "" {
+		attributes = e.keyAttributes
+	}
+	s := &sink{
+		writer:        e.writers.Writer(key),
+		wb:            &writerBuffer{flushed: false, limit: writerBufferLimit, buffer: make([][]string, 0, 100)},
+		attributes:    append(make([]string, 0, len(attributes)), attributes...),
+		attributesSet: make(map[string]bool, len(attributes)),
+	}
+	for _, attr := range attributes {
+		s.attributesSet[attr] = true
+	}
+	if e.columns != "" {
+		_ = s.writer.WriteHeader(s.attributes)
+		// Consider if columns are set do not use buffer and flush all directly to the writer
+		s.wb.flushed = true
+	}
+	e.sinks[key] = s
+	e.keys = append(e.keys, key)
+	return s
+}
 
 func (qp *QueryParams) hashKeyConditionBuilder(
 	key *dynamodb.KeySchemaElement, definitions map[string]string) expression.KeyConditionBuilder {
@@ -118,26 +187,41 @@ func parse(attributeValue string, attributeType string) interface{} {
 }
 
 func (qp *QueryParams) isEmpty() bool {
-	return len(qp.Hash) == 0
+	return len(qp.Hash) == 0 && len(qp.Hashes) == 0 && qp.Keys == nil && qp.Statement == nil
+}
+
+// withHash returns the copy of the query params with the single hash set.
+func (qp *QueryParams) withHash(hash string) *QueryParams {
+	params := *qp
+	params.Hash = hash
+	params.Hashes = nil
+	return &params
 }
 
 func (qp *QueryParams) hasSort() bool {
 	return len(qp.Sort) != 0 || len(qp.SortGt) != 0 || len(qp.SortGe) != 0 || len(qp.SortLt) != 0 ||
-		len(qp.SortLe) != 0 || len(qp.SortBeginsWith) != 0 || len(qp.SortBetween) != 0
+		len(qp.SortLe) != 0 || len(qp.SortBeginsWith) != 0 || len(qp.SortBetween) != 0 || qp.hasTimeRange()
 }
 
+// keyConditionExpression builds the key condition expression, and the filter expression if the filter is set.
 func (qp *QueryParams) keyConditionExpression(
-	keys []*dynamodb.KeySchemaElement, definitions []*dynamodb.AttributeDefinition) expression.Expression {
-	definitionsMapping := make(map[string]string)
-	for _, definition := range definitions {
-		definitionsMapping[aws.StringValue(definition.AttributeName)] = aws.StringValue(definition.AttributeType)
-	}
-	hashKeyConditionBuilder := qp.hashKeyConditionBuilder(findHashKey(keys), definitionsMapping)
+	keys []*dynamodb.KeySchemaElement, definitions []*dynamodb.AttributeDefinition, filter Filter) expression.Expression {
+	mapping := definitionsMapping(definitions)
+	hashKeyConditionBuilder := qp.hashKeyConditionBuilder(findHashKey(keys), mapping)
 	keyConditionBuilder := hashKeyConditionBuilder
 	if qp.hasSort() {
-		keyConditionBuilder = hashKeyConditionBuilder.And(qp.sortKeyConditionBuilder(findRangeKey(keys), definitionsMapping))
+		sortKey := findRangeKey(keys)
+		if sortKey == nil {
+			log.Panic("sort condition requires the sort key")
+		}
+		sqp := qp.withTimeRange(mapping[aws.StringValue(sortKey.AttributeName)])
+		keyConditionBuilder = hashKeyConditionBuilder.And(sqp.sortKeyConditionBuilder(sortKey, mapping))
+	}
+	builder := expression.NewBuilder().WithKeyCondition(keyConditionBuilder)
+	if len(filter) != 0 {
+		builder = builder.WithFilter(filter.conditionBuilder(mapping))
 	}
-	expr, err := expression.NewBuilder().WithKeyCondition(keyConditionBuilder).Build()
+	expr, err := builder.Build()
 	if err != nil {
 		log.Panicf("failed to build query expression due to %v", err)
 	}
@@ -161,72 +245,108 @@ func findKeyByType(keys []*dynamodb.KeySchemaElement, keyType string) *dynamodb.
 	return nil
 }
 
-// ExportToCSV exports the result of the scan or query from the table into the corresponding CSV file using provided
-// table and other settings.
+// ExportToCSV exports the result of the scan or query from the table into the corresponding CSV outputs using provided
+// table and other settings. It returns the CSV headers of the outputs (by their keys) which got new attributes detected
+// after the CSV headers have been already written.
 func ExportToCSV(
-	profile string, table string, index string, qp *QueryParams, columns string, skipColumns string, limit uint, w io.Writer) ([]string, bool) {
-	svc := dynamodb.New(awssessions.GetSession(profile))
-	writer := csv.NewWriter(w)
-	attributes := make([]string, 0)
-	if columns != "" {
-		attributes = strings.Split(columns, columnsSeparator)
-		_ = writer.Write(attributes)
-		// Consider if columns are set do not use buffer and flush all directly to the writer
-		wb.flushed = true
+	sp *awssessions.SessionParams,
+	table string,
+	index string,
+	qp *QueryParams,
+	filter Filter,
+	columns string,
+	skipColumns string,
+	limit uint,
+	concurrency uint,
+	writers Writers) map[string][]string {
+
+	svc := dynamodb.New(awssessions.GetSession(sp))
+	e := newExporter(columns, skipColumns, limit, writers)
+	var desc *dynamodb.TableDescription
+	if columns == "" || !qp.isEmpty() || len(filter) != 0 || qp.Incremental != nil {
+		desc = describe(svc, table)
 	}
-	skipAttributes := make(map[string]bool)
-	if skipColumns != "" {
-		for _, attr := range strings.Split(skipColumns, columnsSeparator) {
-			skipAttributes[attr] = true
+	var err error
+	if qp.Incremental != nil {
+		if qp, filter, err = qp.Incremental.apply(desc, index, qp, filter); err != nil {
+			log.Panic(err)
 		}
+		e.incremental = qp.Incremental
 	}
-	var desc *dynamodb.TableDescription
-	attributesSet := make(map[string]bool)
 	if columns == "" {
-		output, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
-		if err != nil {
-			log.Panicf("error fetching table %s description %v", table, err)
-		}
-		desc = output.Table
-		attributes, attributesSet = defineBaselineAttributes(
-			svc, desc, desc.GlobalSecondaryIndexes, index, skipAttributes)
-	}
-	var err error
-	if qp.isEmpty() {
-		attributes, err = scanPages(svc, table, columns, limit, attributes, skipAttributes, attributesSet, writer)
+		e.attributes, e.attributesSet = defineBaselineAttributes(
+			svc, desc, desc.GlobalSecondaryIndexes, index, e.skipAttributes)
+		e.keyAttributes = defineKeyAttributes(desc, index, e.skipAttributes)
+	}
+	for _, key := range writers.Initial() {
+		e.sink(key)
+	}
+	if qp.Statement != nil {
+		err = statementPages(svc, qp.Statement, e)
+	} else if qp.isEmpty() {
+		err = scanPages(svc, desc, table, filter, e)
+	} else if qp.Keys != nil {
+		err = batchGetPages(svc, desc, table, qp.Keys, e)
+	} else if len(qp.Hashes) != 0 {
+		err = queryHashesPages(svc, desc, table, index, qp, filter, concurrency, e)
 	} else {
-		attributes, err = queryPages(
-			svc, desc, table, index, qp, columns, limit, attributes, skipAttributes, attributesSet, writer)
+		err = queryPages(svc, desc, table, index, qp, filter, e)
 	}
 	if err != nil {
 		log.Panic(err)
 	}
-	return attributes, forceAttributesStdout
+	return e.extendedHeaders()
 }
 
+func newExporter(columns string, skipColumns string, limit uint, writers Writers) *exporter {
+	e := &exporter{
+		columns:        columns,
+		skipAttributes: make(map[string]bool),
+		attributes:     make([]string, 0),
+		attributesSet:  make(map[string]bool),
+		limit:          limit,
+		writers:        writers,
+		sinks:          make(map[string]*sink),
+	}
+	if columns != "" {
+		e.attributes = strings.Split(columns, columnsSeparator)
+	}
+	if skipColumns != "" {
+		for _, attr := range strings.Split(skipColumns, columnsSeparator) {
+			e.skipAttributes[attr] = true
+		}
+	}
+	return e
+}
+
+// extendedHeaders returns the CSV headers of the outputs (by their keys) which got new attributes detected after the
+// CSV headers have been already written.
+func (e *exporter) extendedHeaders() map[string][]string {
+	headers := make(map[string][]string)
+	for key, s := range e.sinks {
+		if s.forceAttributesStdout {
+			headers[key] = s.attributes
+		}
+	}
+	return headers
+}
+
+// scanPages scans the table, the index is only queried (see queryPages), as the export of the index scan would have
+// only the attributes projected into it.
 func scanPages(
-	svc *dynamodb.DynamoDB,
-	table string,
-	columns string,
-	limit uint,
-	attributes []string,
-	skipAttributes map[string]bool,
-	attributesSet map[string]bool,
-	writer *csv.Writer) ([]string, error) {
+	svc dynamodbiface.DynamoDBAPI, desc *dynamodb.TableDescription, table string, filter Filter, e *exporter) error {
 
 	scan := dynamodb.ScanInput{TableName: aws.String(table)}
-	if limit > 0 {
-		scan.Limit = aws.Int64(int64(limit))
+	if len(filter) != 0 {
+		expr := filter.filterExpression(desc.AttributeDefinitions)
+		scan.FilterExpression = expr.Filter()
+		scan.ExpressionAttributeNames = expr.Names()
+		scan.ExpressionAttributeValues = expr.Values()
 	}
-	processed := 0
-	err := svc.ScanPages(&scan,
+	return svc.ScanPages(&scan,
 		func(page *dynamodb.ScanOutput, lastPage bool) bool {
-			done := false
-			attributes, attributesSet, processed, done = process(
-				page.Items, columns, attributes, skipAttributes, attributesSet, limit, processed, lastPage, writer)
-			return !done
+			return !e.process(page.Items, lastPage)
 		})
-	return attributes, err
 }
 
 func queryPages(
@@ -235,52 +355,134 @@ func queryPages(
 	table string,
 	index string,
 	qp *QueryParams,
-	columns string,
-	limit uint,
-	attributes []string,
-	skipAttributes map[string]bool,
-	attributesSet map[string]bool,
-	writer *csv.Writer) ([]string, error) {
+	filter Filter,
+	e *exporter) error {
 
-	if desc == nil {
-		output, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
-		if err != nil {
-			log.Panicf("error fetching table %s description %v", table, err)
-		}
-		desc = output.Table
-	}
+	return svc.QueryPages(queryInput(desc, table, index, qp, filter),
+		func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			return !e.process(page.Items, lastPage)
+		})
+}
 
-	var keySchema = desc.KeySchema
-	if index != "" {
-		indexes := desc.GlobalSecondaryIndexes
-		for _, idx := range indexes {
-			if aws.StringValue(idx.IndexName) == index {
-				keySchema = idx.KeySchema
-				break
+// queryHashesPages runs the query for each of the hashes, at most concurrency queries at the same time, and processes
+// the items in the order of the hashes, so the output is the same as if the queries were run one after another. The
+// queries ahead of the one being processed keep at most hashPagesBuffer pages in memory.
+func queryHashesPages(
+	svc dynamodbiface.DynamoDBAPI,
+	desc *dynamodb.TableDescription,
+	table string,
+	index string,
+	qp *QueryParams,
+	filter Filter,
+	concurrency uint,
+	e *exporter) error {
+
+	if concurrency == 0 {
+		concurrency = 1
+	}
+	done := make(chan struct{})
+	defer close(done)
+	results := make(chan *hashPages, concurrency)
+	go func() {
+		defer close(results)
+		running := make(chan struct{}, concurrency)
+		for _, hash := range qp.Hashes {
+			hp := &hashPages{pages: make(chan []map[string]*dynamodb.AttributeValue, hashPagesBuffer)}
+			select {
+			case running <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case results <- hp:
+			case <-done:
+				return
 			}
+			go func(input *dynamodb.QueryInput) {
+				defer func() { <-running }()
+				defer close(hp.pages)
+				hp.err = svc.QueryPages(input,
+					func(page *dynamodb.QueryOutput, lastPage bool) bool {
+						select {
+						case hp.pages <- page.Items:
+							return true
+						case <-done:
+							return false
+						}
+					})
+			}(queryInput(desc, table, index, qp.withHash(hash), filter))
+		}
+	}()
+	for hp := range results {
+		for items := range hp.pages {
+			if e.process(items, false) {
+				return nil
+			}
+		}
+		if hp.err != nil {
+			return hp.err
 		}
 	}
-	expr := qp.keyConditionExpression(keySchema, desc.AttributeDefinitions)
-	query := dynamodb.QueryInput{
+	e.flush(true)
+	return nil
+}
+
+const hashPagesBuffer = 4
+
+// hashPages represents the pages of the single hash query, err is set once pages are closed.
+type hashPages struct {
+	pages chan []map[string]*dynamodb.AttributeValue
+	err   error
+}
+
+func queryInput(
+	desc *dynamodb.TableDescription,
+	table string,
+	index string,
+	qp *QueryParams,
+	filter Filter) *dynamodb.QueryInput {
+
+	expr := qp.keyConditionExpression(indexKeySchema(desc, index), desc.AttributeDefinitions, filter)
+	query := &dynamodb.QueryInput{
 		TableName:                 aws.String(table),
 		KeyConditionExpression:    expr.KeyCondition(),
+		FilterExpression:          expr.Filter(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values()}
 	if index != "" {
 		query.IndexName = aws.String(index)
 	}
-	if limit > 0 {
-		query.Limit = aws.Int64(int64(limit))
+	if qp.Desc {
+		query.ScanIndexForward = aws.Bool(false)
 	}
-	processed := 0
-	err := svc.QueryPages(&query,
-		func(page *dynamodb.QueryOutput, lastPage bool) bool {
-			done := false
-			attributes, attributesSet, processed, done = process(
-				page.Items, columns, attributes, skipAttributes, attributesSet, limit, processed, lastPage, writer)
-			return !done
-		})
-	return attributes, err
+	return query
+}
+
+func describe(svc dynamodbiface.DynamoDBAPI, table string) *dynamodb.TableDescription {
+	output, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		log.Panicf("error fetching table %s description %v", table, err)
+	}
+	return output.Table
+}
+
+// indexKeySchema returns the key schema of the global or local secondary index, or the table's one if the index is not
+// set.
+func indexKeySchema(desc *dynamodb.TableDescription, index string) []*dynamodb.KeySchemaElement {
+	if index == "" {
+		return desc.KeySchema
+	}
+	for _, idx := range desc.GlobalSecondaryIndexes {
+		if aws.StringValue(idx.IndexName) == index {
+			return idx.KeySchema
+		}
+	}
+	for _, idx := range desc.LocalSecondaryIndexes {
+		if aws.StringValue(idx.IndexName) == index {
+			return idx.KeySchema
+		}
+	}
+	return desc.KeySchema
 }
 
 // Gets one single item from the table (using scan), and build the baseline attributes out of there, where table's
@@ -347,6 +549,23 @@ func defineBaselineAttributes(
 	return attributes, attributesSet
 }
 
+// Builds the primary key attributes, i.e. table's hash/sort keys, or if the index is set, then index's hash/sort keys
+// first and table's next.
+func defineKeyAttributes(
+	table *dynamodb.TableDescription, index string, skipAttributes map[string]bool) []string {
+
+	attributes := make([]string, 0)
+	attributesSet := make(map[string]bool)
+	for _, i := range table.GlobalSecondaryIndexes {
+		if aws.StringValue(i.IndexName) == index {
+			attributes, attributesSet = appendKeyAttributes(i.KeySchema, attributes, attributesSet, skipAttributes)
+			break
+		}
+	}
+	attributes, _ = appendKeyAttributes(table.KeySchema, attributes, attributesSet, skipAttributes)
+	return attributes
+}
+
 func appendKeyAttributes(
 	keys []*dynamodb.KeySchemaElement,
 	attributes []string,
@@ -374,65 +593,79 @@ func shouldAppendAttribute(name string, attributesSet map[string]bool, skipAttri
 	return !skipAttributes[name] && !attributesSet[name]
 }
 
-func process(
-	items []map[string]*dynamodb.AttributeValue,
-	columns string,
-	attributes []string,
-	skipAttributes map[string]bool,
-	attributesSet map[string]bool,
-	limit uint,
-	processed int,
-	lastPage bool,
-	writer *csv.Writer) ([]string, map[string]bool, int, bool) {
+// process routes the items into the corresponding sinks, and returns whether the export is done, i.e. either the limit
+// has been reached or this is the last page.
+func (e *exporter) process(items []map[string]*dynamodb.AttributeValue, lastPage bool) bool {
 	for _, item := range items {
-		records := make(map[string]string)
-		for k, av := range item {
-			value, handled := getValue(av)
-			if !handled {
+		if e.incremental != nil {
+			if e.incremental.exportedBefore(item) {
 				continue
 			}
-			if columns == "" {
-				if shouldAppendAttribute(k, attributesSet, skipAttributes) {
-					attributesSet[k] = true
-					if wb.flushed {
-						forceAttributesStdout = true
-					}
-					attributes = append(attributes, k)
-				}
-			}
-			records[k] = value
+			e.incremental.track(item)
 		}
-		orderedRecords := make([]string, 0, len(attributes))
-		for _, attr := range attributes {
-			if value, ok := records[attr]; ok {
-				orderedRecords = append(orderedRecords, value)
-			} else {
-				orderedRecords = append(orderedRecords, "")
-			}
+		e.sink(e.writers.Route(item)).process(item, e.columns, e.skipAttributes)
+		e.processed++
+		if e.limit > 0 && e.processed == int(e.limit) {
+			e.flush(true)
+			return true
 		}
-		if wb.flushed {
-			_ = writer.Write(orderedRecords)
+	}
+	e.flush(lastPage)
+	return lastPage
+}
+
+// flush flushes the already written records of all sinks, and if force is set also the records buffered in memory.
+func (e *exporter) flush(force bool) {
+	for _, key := range e.keys {
+		s := e.sinks[key]
+		if force && !s.wb.flushed {
+			s.wb.flush(s.writer, s.attributes)
 		} else {
-			wb.buffer = append(wb.buffer, orderedRecords)
-			if len(wb.buffer) >= wb.limit {
-				wb.flush(writer, attributes)
-			}
+			_ = s.writer.Flush()
+		}
+	}
+}
+
+func (s *sink) process(item map[string]*dynamodb.AttributeValue, columns string, skipAttributes map[string]bool) {
+	records := make(map[string]string)
+	detected := false
+	for k, av := range item {
+		value, handled := getValue(av)
+		if !handled {
+			continue
 		}
-		processed++
-		if limit > 0 && processed == int(limit) {
-			if wb.flushed {
-				writer.Flush()
-			} else {
-				wb.flush(writer, attributes)
+		if columns == "" {
+			if shouldAppendAttribute(k, s.attributesSet, skipAttributes) {
+				s.attributesSet[k] = true
+				if s.wb.flushed {
+					s.forceAttributesStdout = true
+					detected = true
+				}
+				s.attributes = append(s.attributes, k)
 			}
-			return attributes, attributesSet, processed, true
+		}
+		records[k] = value
+	}
+	if detected {
+		// let the writer know about the extended header, i.e. to use it for the next output part if supported
+		_ = s.writer.WriteHeader(s.attributes)
+	}
+	orderedRecords := make([]string, 0, len(s.attributes))
+	for _, attr := range s.attributes {
+		if value, ok := records[attr]; ok {
+			orderedRecords = append(orderedRecords, value)
+		} else {
+			orderedRecords = append(orderedRecords, "")
 		}
 	}
-	if lastPage && !wb.flushed {
-		wb.flush(writer, attributes)
+	if s.wb.flushed {
+		_ = s.writer.Write(orderedRecords)
+	} else {
+		s.wb.buffer = append(s.wb.buffer, orderedRecords)
+		if len(s.wb.buffer) >= s.wb.limit {
+			s.wb.flush(s.writer, s.attributes)
+		}
 	}
-	writer.Flush()
-	return attributes, attributesSet, processed, lastPage
 }
 
 func getValue(av *dynamodb.AttributeValue) (string, bool) {
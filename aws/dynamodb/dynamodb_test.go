@@ -1,12 +1,15 @@
 package dynamodb
 
 import (
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestGetValue(t *testing.T) {
@@ -698,7 +701,7 @@ func TestQueryParamsKeyConditionExpression(t *testing.T) {
 				SortBeginsWith: tt.fields.SortBeginsWith,
 				SortBetween:    tt.fields.SortBetween,
 			}
-			if expr := qp.keyConditionExpression(tt.args.key, tt.args.definitions); aws.StringValue(expr.KeyCondition()) != aws.StringValue(tt.want.KeyCondition()) &&
+			if expr := qp.keyConditionExpression(tt.args.key, tt.args.definitions, nil); aws.StringValue(expr.KeyCondition()) != aws.StringValue(tt.want.KeyCondition()) &&
 				!reflect.DeepEqual(expr.Values(), tt.want.Values()) &&
 				!reflect.DeepEqual(expr.Names(), tt.want.Names()) {
 				t.Errorf("keyConditionExpression() = %v, %v, %v, want %v, %v, %v",
@@ -1018,3 +1021,100 @@ func TestDefineBaselineAttributes(t *testing.T) {
 		})
 	}
 }
+
+type hashesDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+// QueryPages returns 2 pages with 2 items each per hash, the later hashes finish first.
+func (m hashesDynamoDBClient) QueryPages(
+	input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	hash := aws.StringValue(input.ExpressionAttributeValues[":0"].S)
+	n, _ := strconv.Atoi(hash)
+	time.Sleep(time.Duration(10-n) * time.Millisecond)
+	for page := 0; page < 2; page++ {
+		items := []map[string]*dynamodb.AttributeValue{
+			{"Id": {S: aws.String(fmt.Sprintf("%s-%d", hash, page*2))}},
+			{"Id": {S: aws.String(fmt.Sprintf("%s-%d", hash, page*2+1))}},
+		}
+		if !fn(&dynamodb.QueryOutput{Items: items}, page == 1) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func TestQueryHashesPages(t *testing.T) {
+	desc := &dynamodb.TableDescription{
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("Id"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("Id"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+	}
+	hashes := []string{"1", "2", "3", "4", "5", "6"}
+	tests := []struct {
+		name  string
+		limit uint
+		want  int
+	}{
+		{name: "all", want: 24},
+		{name: "limit", limit: 7, want: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &recordingWriter{}
+			e := &exporter{
+				columns:        "Id",
+				skipAttributes: map[string]bool{},
+				attributes:     []string{"Id"},
+				attributesSet:  map[string]bool{"Id": true},
+				limit:          tt.limit,
+				writers:        SingleWriter(writer),
+				sinks:          make(map[string]*sink),
+			}
+			err := queryHashesPages(
+				hashesDynamoDBClient{}, desc, "t1", "", &QueryParams{Hashes: hashes}, nil, 3, e)
+			if err != nil {
+				t.Fatalf("queryHashesPages() error = %v", err)
+			}
+			want := make([][]string, 0, tt.want)
+			for _, hash := range hashes {
+				for i := 0; i < 4 && len(want) < tt.want; i++ {
+					want = append(want, []string{fmt.Sprintf("%s-%d", hash, i)})
+				}
+			}
+			if !reflect.DeepEqual(writer.records, want) {
+				t.Errorf("queryHashesPages() = %v, want %v", writer.records, want)
+			}
+		})
+	}
+}
+
+func TestQueryInput(t *testing.T) {
+	filter := Filter{{Attribute: "total", Operator: filterGreaterThan, Value: "100"}}
+	tests := []struct {
+		name                 string
+		qp                   *QueryParams
+		filter               Filter
+		wantScanIndexForward *bool
+	}{
+		{name: "ascending", qp: &QueryParams{Hash: "c1"}},
+		{name: "descending", qp: &QueryParams{Hash: "c1", Desc: true}, wantScanIndexForward: aws.Bool(false)},
+		{name: "filter", qp: &QueryParams{Hash: "c1"}, filter: filter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := queryInput(ordersDescription, "orders", "", tt.qp, tt.filter)
+			if !reflect.DeepEqual(got.ScanIndexForward, tt.wantScanIndexForward) {
+				t.Errorf("queryInput() ScanIndexForward = %v, want %v",
+					aws.BoolValue(got.ScanIndexForward), aws.BoolValue(tt.wantScanIndexForward))
+			}
+			// the limit is applied exactly by the exporter across the pages, so it is never the page size
+			if got.Limit != nil {
+				t.Errorf("queryInput() Limit = %v, want nil", aws.Int64Value(got.Limit))
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/zshamrock/dynocsv/output"
+	"log"
+	"strings"
+)
+
+const (
+	filterEqual          = "="
+	filterNotEqual       = "!="
+	filterLessThan       = "<"
+	filterLessOrEqual    = "<="
+	filterGreaterThan    = ">"
+	filterGreaterOrEqual = ">="
+	filterBeginsWith     = "^="
+	filterContains       = "~="
+	filterExists         = ""
+	filterNotExists      = "!"
+
+	filterNotExistsPrefix = "!"
+	filterTypeSeparator   = ":"
+)
+
+// filterOperators are ordered so the two symbols operators are matched before the single symbol ones they start with.
+var filterOperators = []string{
+	filterNotEqual,
+	filterLessOrEqual,
+	filterGreaterOrEqual,
+	filterBeginsWith,
+	filterContains,
+	filterEqual,
+	filterLessThan,
+	filterGreaterThan,
+}
+
+// FilterCondition represents the single condition on the attribute, i.e. "status=active" or "amount>=100".
+type FilterCondition struct {
+	Attribute string
+	Operator  string
+	Value     string
+	// Type is either S or N if set explicitly, i.e. "zip:S=02134", otherwise it is resolved from the table's attribute
+	// definitions, or as N if the value looks like a number, and S otherwise
+	Type string
+}
+
+// Filter represents the conditions the fetched items have to match (all of them), applied by DynamoDB after the items
+// are read, so they don't reduce the consumed capacity, but reduce the transferred data.
+type Filter []FilterCondition
+
+// ParseFilter parses the conditions in the form of <attribute><operator><value>, where the operator is one of =, !=,
+// <, <=, >, >=, ^= (begins with) or ~= (contains). The bare <attribute> matches the items which have the attribute,
+// and !<attribute> the ones which don't.
+func ParseFilter(conditions []string) (Filter, error) {
+	filter := make(Filter, 0, len(conditions))
+	for _, condition := range conditions {
+		fc, err := parseFilterCondition(condition)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, fc)
+	}
+	return filter, nil
+}
+
+func parseFilterCondition(condition string) (FilterCondition, error) {
+	index, operator := -1, ""
+	for _, op := range filterOperators {
+		if i := strings.Index(condition, op); i != -1 && (index == -1 || i < index) {
+			index, operator = i, op
+		}
+	}
+	var fc FilterCondition
+	if index == -1 {
+		fc = FilterCondition{Attribute: condition, Operator: filterExists}
+		if strings.HasPrefix(condition, filterNotExistsPrefix) {
+			fc = FilterCondition{Attribute: strings.TrimPrefix(condition, filterNotExistsPrefix), Operator: filterNotExists}
+		}
+	} else {
+		fc = FilterCondition{
+			Attribute: condition[:index],
+			Operator:  operator,
+			Value:     condition[index+len(operator):],
+		}
+		if i := strings.LastIndex(fc.Attribute, filterTypeSeparator); i != -1 {
+			fc.Attribute, fc.Type = fc.Attribute[:i], fc.Attribute[i+len(filterTypeSeparator):]
+			if fc.Type != dynamodb.ScalarAttributeTypeS && fc.Type != dynamodb.ScalarAttributeTypeN {
+				return FilterCondition{}, fmt.Errorf(
+					"unsupported filter %q value type %q, only S and N are supported", condition, fc.Type)
+			}
+		}
+	}
+	if fc.Attribute == "" {
+		return FilterCondition{}, fmt.Errorf("filter %q has no attribute name", condition)
+	}
+	return fc, nil
+}
+
+func (f Filter) conditionBuilder(definitions map[string]string) expression.ConditionBuilder {
+	builder := f[0].conditionBuilder(definitions)
+	for _, fc := range f[1:] {
+		builder = builder.And(fc.conditionBuilder(definitions))
+	}
+	return builder
+}
+
+func (fc FilterCondition) conditionBuilder(definitions map[string]string) expression.ConditionBuilder {
+	name := expression.Name(fc.Attribute)
+	switch fc.Operator {
+	case filterExists:
+		return expression.AttributeExists(name)
+	case filterNotExists:
+		return expression.AttributeNotExists(name)
+	case filterBeginsWith:
+		return expression.BeginsWith(name, fc.Value)
+	case filterContains:
+		return expression.Contains(name, fc.Value)
+	}
+	value := expression.Value(fc.value(definitions))
+	switch fc.Operator {
+	case filterEqual:
+		return expression.Equal(name, value)
+	case filterNotEqual:
+		return expression.NotEqual(name, value)
+	case filterLessThan:
+		return expression.LessThan(name, value)
+	case filterLessOrEqual:
+		return expression.LessThanEqual(name, value)
+	case filterGreaterThan:
+		return expression.GreaterThan(name, value)
+	case filterGreaterOrEqual:
+		return expression.GreaterThanEqual(name, value)
+	}
+	log.Panicf("unsupported filter operator %s", fc.Operator)
+	return expression.ConditionBuilder{}
+}
+
+func (fc FilterCondition) value(definitions map[string]string) interface{} {
+	attributeType := fc.Type
+	if attributeType == "" {
+		attributeType = definitions[fc.Attribute]
+	}
+	if attributeType == "" && output.IsNumber(fc.Value) {
+		attributeType = dynamodb.ScalarAttributeTypeN
+	}
+	if attributeType == dynamodb.ScalarAttributeTypeN {
+		// keep the number as is, so it doesn't lose the precision DynamoDB numbers could have
+		return dynamodbattribute.Number(fc.Value)
+	}
+	return fc.Value
+}
+
+// filterExpression builds the filter expression, the filter must have at least one condition.
+func (f Filter) filterExpression(definitions []*dynamodb.AttributeDefinition) expression.Expression {
+	expr, err := expression.NewBuilder().WithFilter(f.conditionBuilder(definitionsMapping(definitions))).Build()
+	if err != nil {
+		log.Panicf("failed to build filter expression due to %v", err)
+	}
+	return expr
+}
+
+func definitionsMapping(definitions []*dynamodb.AttributeDefinition) map[string]string {
+	mapping := make(map[string]string)
+	for _, definition := range definitions {
+		mapping[aws.StringValue(definition.AttributeName)] = aws.StringValue(definition.AttributeType)
+	}
+	return mapping
+}
@@ -0,0 +1,109 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []string
+		want       Filter
+		wantErr    bool
+	}{
+		{
+			name:       "comparisons",
+			conditions: []string{"status=active", "amount>=100", "amount<1000", "kind!=test", "sk<=b", "ts>1"},
+			want: Filter{
+				{Attribute: "status", Operator: filterEqual, Value: "active"},
+				{Attribute: "amount", Operator: filterGreaterOrEqual, Value: "100"},
+				{Attribute: "amount", Operator: filterLessThan, Value: "1000"},
+				{Attribute: "kind", Operator: filterNotEqual, Value: "test"},
+				{Attribute: "sk", Operator: filterLessOrEqual, Value: "b"},
+				{Attribute: "ts", Operator: filterGreaterThan, Value: "1"},
+			},
+		},
+		{
+			name:       "functions",
+			conditions: []string{"sk^=ORDER#", "tags~=red", "email", "!deletedAt"},
+			want: Filter{
+				{Attribute: "sk", Operator: filterBeginsWith, Value: "ORDER#"},
+				{Attribute: "tags", Operator: filterContains, Value: "red"},
+				{Attribute: "email", Operator: filterExists},
+				{Attribute: "deletedAt", Operator: filterNotExists},
+			},
+		},
+		{
+			name:       "value with operator symbols",
+			conditions: []string{"expr=a>=b"},
+			want:       Filter{{Attribute: "expr", Operator: filterEqual, Value: "a>=b"}},
+		},
+		{
+			name:       "explicit type",
+			conditions: []string{"zip:S=02134"},
+			want:       Filter{{Attribute: "zip", Operator: filterEqual, Value: "02134", Type: dynamodb.ScalarAttributeTypeS}},
+		},
+		{
+			name:       "unsupported type",
+			conditions: []string{"zip:BOOL=true"},
+			wantErr:    true,
+		},
+		{
+			name:       "no attribute",
+			conditions: []string{"=active"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.conditions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterExpression(t *testing.T) {
+	filter := Filter{
+		{Attribute: "status", Operator: filterEqual, Value: "active"},
+		{Attribute: "amount", Operator: filterGreaterOrEqual, Value: "100.5"},
+		{Attribute: "zip", Operator: filterEqual, Value: "02134", Type: dynamodb.ScalarAttributeTypeS},
+		{Attribute: "code", Operator: filterEqual, Value: "7"},
+		{Attribute: "deletedAt", Operator: filterNotExists},
+	}
+	definitions := []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String("code"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+	}
+	expr := filter.filterExpression(definitions)
+	want := "((((#0 = :0) AND (#1 >= :1)) AND (#2 = :2)) AND (#3 = :3)) AND (attribute_not_exists (#4))"
+	if got := aws.StringValue(expr.Filter()); got != want {
+		t.Errorf("filterExpression() = %v, want %v", got, want)
+	}
+	wantNames := map[string]*string{
+		"#0": aws.String("status"),
+		"#1": aws.String("amount"),
+		"#2": aws.String("zip"),
+		"#3": aws.String("code"),
+		"#4": aws.String("deletedAt"),
+	}
+	if got := expr.Names(); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("filterExpression() names = %v, want %v", got, wantNames)
+	}
+	wantValues := map[string]*dynamodb.AttributeValue{
+		":0": {S: aws.String("active")},
+		":1": {N: aws.String("100.5")},
+		":2": {S: aws.String("02134")},
+		":3": {S: aws.String("7")},
+	}
+	if got := expr.Values(); !reflect.DeepEqual(got, wantValues) {
+		t.Errorf("filterExpression() values = %v, want %v", got, wantValues)
+	}
+}
@@ -0,0 +1,285 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"github.com/zshamrock/dynocsv/output"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	typesSeparator = ","
+	typeSeparator  = "="
+)
+
+// attributeTypes are the attribute types the CSV values could be imported as.
+var attributeTypes = map[string]bool{
+	dynamodb.ScalarAttributeTypeS: true,
+	dynamodb.ScalarAttributeTypeN: true,
+	dynamodb.ScalarAttributeTypeB: true,
+	"BOOL":                        true,
+	"NULL":                        true,
+	"SS":                          true,
+	"NS":                          true,
+	"BS":                          true,
+	"L":                           true,
+	"M":                           true,
+}
+
+// ParseTypes parses the attribute types, i.e. "age=N,tags=SS".
+func ParseTypes(types string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	if types == "" {
+		return parsed, nil
+	}
+	for _, t := range strings.Split(types, typesSeparator) {
+		parts := strings.SplitN(t, typeSeparator, 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid type %q, expected <attribute>=<type>, i.e. age=N", t)
+		}
+		attribute, attributeType := strings.TrimSpace(parts[0]), strings.ToUpper(strings.TrimSpace(parts[1]))
+		if !attributeTypes[attributeType] {
+			names := make([]string, 0, len(attributeTypes))
+			for name := range attributeTypes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unsupported %s type %q, supported are %s",
+				attribute, attributeType, strings.Join(names, ", "))
+		}
+		parsed[attribute] = attributeType
+	}
+	return parsed, nil
+}
+
+// ImportFromCSV imports the CSV, where the header names the attributes, into the table, and returns the number of the
+// items written. The values are converted into the attribute types set by types, or the key attributes types, or the
+// types inferred from the values. At most rate items are written per second (0 means no limit).
+func ImportFromCSV(
+	sp *awssessions.SessionParams, table string, r io.Reader, types map[string]string, rate float64) (int64, error) {
+
+	svc := dynamodb.New(awssessions.GetSession(sp))
+	return importItems(svc, describe(svc, table), table, r, types, rate)
+}
+
+func importItems(
+	svc dynamodbiface.DynamoDBAPI,
+	desc *dynamodb.TableDescription,
+	table string,
+	r io.Reader,
+	types map[string]string,
+	rate float64) (int64, error) {
+
+	reader := csv.NewReader(r)
+	// the rows after the late detected attributes are longer than the header, which is checked below
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return 0, fmt.Errorf("header is missing")
+	}
+	if err != nil {
+		return 0, err
+	}
+	definitions := definitionsMapping(desc.AttributeDefinitions)
+	w := newBatchWriter(svc, desc, table, &rateLimiter{rate: rate})
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return w.written, err
+		}
+		if len(record) > len(header) {
+			return w.written, fmt.Errorf("line %d has %d values, but the header has only %d columns",
+				line, len(record), len(header))
+		}
+		item := make(map[string]*dynamodb.AttributeValue, len(record))
+		for i, value := range record {
+			attribute := header[i]
+			// the missing attributes are exported as the empty values
+			if attribute == "" || value == "" {
+				continue
+			}
+			attributeType, ok := types[attribute]
+			if !ok {
+				attributeType = definitions[attribute]
+			}
+			av, err := toAttributeValue(value, attributeType)
+			if err != nil {
+				return w.written, fmt.Errorf("line %d attribute %s: %v", line, attribute, err)
+			}
+			if av != nil {
+				item[attribute] = av
+			}
+		}
+		if len(item) == 0 {
+			continue
+		}
+		if err := w.put(item); err != nil {
+			return w.written, err
+		}
+	}
+	err = w.flush()
+	return w.written, err
+}
+
+// toAttributeValue converts the value as it is exported by getValue back into the attribute value of the type, or of
+// the inferred type if it is not set. The empty set is returned as nil.
+func toAttributeValue(value string, attributeType string) (*dynamodb.AttributeValue, error) {
+	switch attributeType {
+	case "":
+		return inferAttributeValue(value), nil
+	case dynamodb.ScalarAttributeTypeS:
+		return &dynamodb.AttributeValue{S: aws.String(value)}, nil
+	case dynamodb.ScalarAttributeTypeN:
+		if !output.IsNumber(value) {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return &dynamodb.AttributeValue{N: aws.String(value)}, nil
+	case dynamodb.ScalarAttributeTypeB:
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{B: b}, nil
+	case "BOOL":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(b)}, nil
+	case "NULL":
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}, nil
+	case "M":
+		return parseMap(value)
+	}
+	values, err := parseList(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 && attributeType != "L" {
+		// DynamoDB doesn't allow the empty sets, so the attribute is omitted
+		return nil, nil
+	}
+	switch attributeType {
+	case "L":
+		list := make([]*dynamodb.AttributeValue, 0, len(values))
+		for _, v := range values {
+			list = append(list, inferAttributeValue(v))
+		}
+		return &dynamodb.AttributeValue{L: list}, nil
+	case "SS":
+		return &dynamodb.AttributeValue{SS: aws.StringSlice(distinct(values))}, nil
+	case "NS":
+		for _, v := range values {
+			if !output.IsNumber(v) {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+		}
+		return &dynamodb.AttributeValue{NS: aws.StringSlice(distinct(values))}, nil
+	case "BS":
+		set := make([][]byte, 0, len(values))
+		for _, v := range distinct(values) {
+			b, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, b)
+		}
+		return &dynamodb.AttributeValue{BS: set}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", attributeType)
+}
+
+// inferAttributeValue infers the type from the value: "true" and "false" are BOOL, numbers are N, JSON objects are M,
+// the values in brackets, i.e. "[a,b]", are L, and anything else is S.
+func inferAttributeValue(value string) *dynamodb.AttributeValue {
+	switch {
+	case value == "true" || value == "false":
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(value == "true")}
+	case output.IsNumber(value):
+		return &dynamodb.AttributeValue{N: aws.String(value)}
+	case strings.HasPrefix(value, "{"):
+		if av, err := parseMap(value); err == nil {
+			return av
+		}
+	case strings.HasPrefix(value, listOpenSymbol) && strings.HasSuffix(value, listCloseSymbol):
+		av, _ := toAttributeValue(value, "L")
+		return av
+	}
+	return &dynamodb.AttributeValue{S: aws.String(value)}
+}
+
+// parseList parses the set or list, i.e. "[a,b]", as it is built by buildOutput.
+func parseList(value string) ([]string, error) {
+	if !strings.HasPrefix(value, listOpenSymbol) || !strings.HasSuffix(value, listCloseSymbol) {
+		return nil, fmt.Errorf("%q is not a list, expected %sa%sb%s", value, listOpenSymbol, listValuesSeparator,
+			listCloseSymbol)
+	}
+	inner := strings.TrimSuffix(strings.TrimPrefix(value, listOpenSymbol), listCloseSymbol)
+	if inner == "" {
+		return []string{}, nil
+	}
+	return strings.Split(inner, listValuesSeparator), nil
+}
+
+// parseMap parses the JSON object, the string values (processMap exports all the values as strings) have their types
+// inferred.
+func parseMap(value string) (*dynamodb.AttributeValue, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.UseNumber()
+	var m map[string]interface{}
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("%q is not a JSON object: %v", value, err)
+	}
+	return jsonAttributeValue(m), nil
+}
+
+func jsonAttributeValue(v interface{}) *dynamodb.AttributeValue {
+	switch value := v.(type) {
+	case nil:
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}
+	case bool:
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(value)}
+	case json.Number:
+		return &dynamodb.AttributeValue{N: aws.String(value.String())}
+	case string:
+		return inferAttributeValue(value)
+	case []interface{}:
+		list := make([]*dynamodb.AttributeValue, 0, len(value))
+		for _, e := range value {
+			list = append(list, jsonAttributeValue(e))
+		}
+		return &dynamodb.AttributeValue{L: list}
+	case map[string]interface{}:
+		m := make(map[string]*dynamodb.AttributeValue, len(value))
+		for k, e := range value {
+			m[k] = jsonAttributeValue(e)
+		}
+		return &dynamodb.AttributeValue{M: m}
+	}
+	return &dynamodb.AttributeValue{S: aws.String(fmt.Sprint(v))}
+}
+
+func distinct(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
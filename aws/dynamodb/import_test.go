@@ -0,0 +1,149 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", types: "", want: map[string]string{}},
+		{name: "types", types: "age=N, tags=ss", want: map[string]string{"age": "N", "tags": "SS"}},
+		{name: "missing type", types: "age", wantErr: true},
+		{name: "missing attribute", types: "=N", wantErr: true},
+		{name: "unsupported type", types: "age=X", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTypes(tt.types)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTypes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToAttributeValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		attributeType string
+		want          *dynamodb.AttributeValue
+		wantErr       bool
+	}{
+		{name: "inferred string", value: "Hippo", want: &dynamodb.AttributeValue{S: aws.String("Hippo")}},
+		{name: "inferred number", value: "-1.5", want: &dynamodb.AttributeValue{N: aws.String("-1.5")}},
+		{name: "inferred bool", value: "true", want: &dynamodb.AttributeValue{BOOL: aws.Bool(true)}},
+		{
+			name:  "inferred list",
+			value: "[a,1]",
+			want: &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{
+				{S: aws.String("a")}, {N: aws.String("1")},
+			}},
+		},
+		{
+			name:  "inferred map",
+			value: `{"age":"1","name":"Hippo","nested":{"ok":true,"none":null}}`,
+			want: &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+				"age":  {N: aws.String("1")},
+				"name": {S: aws.String("Hippo")},
+				"nested": {M: map[string]*dynamodb.AttributeValue{
+					"ok":   {BOOL: aws.Bool(true)},
+					"none": {NULL: aws.Bool(true)},
+				}},
+			}},
+		},
+		{name: "invalid map is string", value: "{a", want: &dynamodb.AttributeValue{S: aws.String("{a")}},
+		{name: "string type", value: "10", attributeType: "S", want: &dynamodb.AttributeValue{S: aws.String("10")}},
+		{name: "invalid number", value: "ten", attributeType: "N", wantErr: true},
+		{name: "binary", value: "aGk=", attributeType: "B", want: &dynamodb.AttributeValue{B: []byte("hi")}},
+		{
+			name:          "string set",
+			value:         "[a,b,a]",
+			attributeType: "SS",
+			want:          &dynamodb.AttributeValue{SS: aws.StringSlice([]string{"a", "b"})},
+		},
+		{
+			name:          "number set",
+			value:         "[1,2]",
+			attributeType: "NS",
+			want:          &dynamodb.AttributeValue{NS: aws.StringSlice([]string{"1", "2"})},
+		},
+		{name: "invalid number set", value: "[1,b]", attributeType: "NS", wantErr: true},
+		{name: "empty set", value: "[]", attributeType: "SS"},
+		{name: "set without brackets", value: "a,b", attributeType: "SS", wantErr: true},
+		{name: "invalid bool", value: "yes", attributeType: "BOOL", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toAttributeValue(tt.value, tt.attributeType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toAttributeValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("toAttributeValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportItems(t *testing.T) {
+	batchWriteItemBackoff = time.Millisecond
+	svc := &batchWriteDynamoDBClient{}
+	csv := "customerId,orderId,status,tags,\nc1,1,NEW,\"[a,b]\"\nc2,2,,[],\n"
+	written, err := importItems(svc, ordersDescription, "orders", strings.NewReader(csv),
+		map[string]string{"tags": "SS"}, 0)
+	if err != nil {
+		t.Fatalf("importItems() error = %v", err)
+	}
+	if written != 2 {
+		t.Errorf("importItems() = %d, want 2", written)
+	}
+	want := []map[string]*dynamodb.AttributeValue{
+		{
+			"customerId": {S: aws.String("c1")},
+			"orderId":    {N: aws.String("1")},
+			"status":     {S: aws.String("NEW")},
+			"tags":       {SS: aws.StringSlice([]string{"a", "b"})},
+		},
+		{
+			"customerId": {S: aws.String("c2")},
+			"orderId":    {N: aws.String("2")},
+		},
+	}
+	if !reflect.DeepEqual(svc.items, want) {
+		t.Errorf("importItems() items = %v, want %v", svc.items, want)
+	}
+}
+
+func TestImportItemsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{name: "missing header", csv: ""},
+		{name: "invalid key type", csv: "customerId,orderId\nc1,one\n"},
+		{name: "more values than header", csv: "customerId,orderId\nc1,1,extra\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := importItems(&batchWriteDynamoDBClient{}, ordersDescription, "orders",
+				strings.NewReader(tt.csv), map[string]string{}, 0)
+			if err == nil {
+				t.Errorf("importItems() error = nil, want error")
+			}
+		})
+	}
+}
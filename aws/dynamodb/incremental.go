@@ -0,0 +1,185 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+)
+
+// Incremental represents the export of only the items whose change tracking attribute (i.e. updatedAt) is greater
+// than or equal to the watermark, the greatest value of the attribute exported by the last run. The items written
+// after the last run read them could have the same value as the watermark (i.e. of the seconds precision), so the
+// items at the watermark are read again, and only the ones not in WatermarkKeys (the keys of the items exported with
+// the watermark value) are exported. The state is loaded from the file, and saved into it with the new watermark once
+// the export succeeds, so the next run continues from it.
+type Incremental struct {
+	path          string
+	keyAttributes []string
+	exportedKeys  map[string]bool
+	next          *dynamodb.AttributeValue
+	nextKeys      map[string]bool
+	exported      int64
+	Table         string                   `json:"table"`
+	Attribute     string                   `json:"attribute"`
+	Watermark     *dynamodb.AttributeValue `json:"watermark,omitempty"`
+	WatermarkKeys []string                 `json:"watermarkKeys,omitempty"`
+	// Exported is the number of the items exported by the last run, and ExportedAt is when it has finished
+	Exported   int64     `json:"exported"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// LoadIncremental loads the state of the incremental export from the file, or starts the new one (exporting all the
+// items) if the file doesn't exist yet.
+func LoadIncremental(path string, table string, attribute string) (*Incremental, error) {
+	inc := &Incremental{path: path, Table: table, Attribute: attribute}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return inc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, inc); err != nil {
+		return nil, fmt.Errorf("failed to read incremental state %s: %v", path, err)
+	}
+	if inc.Table != table || inc.Attribute != attribute {
+		return nil, fmt.Errorf("incremental state %s is of the table %s by %s, but it is table %s by %s", path,
+			inc.Table, inc.Attribute, table, attribute)
+	}
+	if inc.Watermark != nil && inc.Watermark.S == nil && inc.Watermark.N == nil {
+		return nil, fmt.Errorf("incremental state %s watermark is neither string nor number", path)
+	}
+	return inc, nil
+}
+
+// Save saves the greatest value of the attribute exported as the new watermark together with the keys of the items
+// exported with it (or keeps the previous ones if no items were exported), the state file is replaced at once, so it
+// is never left half written. It should be called only once the export has succeeded, so the failed export is run
+// again from the same watermark.
+func (inc *Incremental) Save() error {
+	if inc.next != nil {
+		inc.Watermark = inc.next
+		inc.WatermarkKeys = make([]string, 0, len(inc.nextKeys))
+		for key := range inc.nextKeys {
+			inc.WatermarkKeys = append(inc.WatermarkKeys, key)
+		}
+		sort.Strings(inc.WatermarkKeys)
+	}
+	inc.Exported = inc.exported
+	inc.ExportedAt = time.Now().UTC()
+	return writeJSONFile(inc.path, inc)
+}
+
+// apply returns the query and the filter selecting only the items greater than or equal to the watermark: the query of
+// the table (or the index) whose sort key is the attribute gets the sort condition, otherwise the filter condition is
+// added. The index can't be queried without the hash value, and the scan is always of the table, so the index without
+// the hash value is rejected rather than the whole table scanned with the filter.
+func (inc *Incremental) apply(
+	desc *dynamodb.TableDescription, index string, qp *QueryParams, filter Filter) (*QueryParams, Filter, error) {
+
+	query := qp.Hash != "" || len(qp.Hashes) != 0
+	if index != "" && !query {
+		return nil, nil, fmt.Errorf("index %s can't be queried without the hash value, and the scan is of the "+
+			"table, so the incremental export by %s requires the hash value, or no index", index, inc.Attribute)
+	}
+	inc.keyAttributes = keyAttributeNames(desc)
+	inc.exportedKeys = make(map[string]bool, len(inc.WatermarkKeys))
+	for _, key := range inc.WatermarkKeys {
+		inc.exportedKeys[key] = true
+	}
+	if inc.Watermark == nil {
+		return qp, filter, nil
+	}
+	value, attributeType := aws.StringValue(inc.Watermark.S), dynamodb.ScalarAttributeTypeS
+	if inc.Watermark.N != nil {
+		value, attributeType = aws.StringValue(inc.Watermark.N), dynamodb.ScalarAttributeTypeN
+	}
+	rangeKey := findRangeKey(indexKeySchema(desc, index))
+	if !query || rangeKey == nil || aws.StringValue(rangeKey.AttributeName) != inc.Attribute {
+		condition := FilterCondition{
+			Attribute: inc.Attribute,
+			Operator:  filterGreaterOrEqual,
+			Value:     value,
+			Type:      attributeType,
+		}
+		return qp, append(filter[:len(filter):len(filter)], condition), nil
+	}
+	if qp.hasSort() {
+		return nil, nil, fmt.Errorf("sort condition can't be used together with the incremental export by the sort "+
+			"key %s", inc.Attribute)
+	}
+	params := *qp
+	params.SortGe = value
+	return &params, filter, nil
+}
+
+// exportedBefore returns whether the item has the watermark value, and it has been already exported by the last run.
+func (inc *Incremental) exportedBefore(item map[string]*dynamodb.AttributeValue) bool {
+	av := item[inc.Attribute]
+	if av == nil || inc.Watermark == nil || len(inc.exportedKeys) == 0 {
+		return false
+	}
+	if c, ok := compare(av, inc.Watermark); !ok || c != 0 {
+		return false
+	}
+	return inc.exportedKeys[keyID(item, inc.keyAttributes)]
+}
+
+// track records the item's attribute value, if it is greater than the ones seen so far, it becomes the next watermark,
+// and the item's key is recorded if it has the next watermark value. The values of the other type than the
+// watermark's one are ignored.
+func (inc *Incremental) track(item map[string]*dynamodb.AttributeValue) {
+	inc.exported++
+	av := item[inc.Attribute]
+	if av == nil || (av.S == nil && av.N == nil) {
+		return
+	}
+	if inc.next == nil && inc.Watermark != nil {
+		// the items at the watermark exported by the last run are still at the watermark if nothing newer is exported
+		inc.next = inc.Watermark
+		inc.nextKeys = make(map[string]bool, len(inc.WatermarkKeys)+1)
+		for _, key := range inc.WatermarkKeys {
+			inc.nextKeys[key] = true
+		}
+	}
+	c, ok := 1, true
+	if inc.next != nil {
+		c, ok = compare(av, inc.next)
+	}
+	if !ok || c < 0 {
+		return
+	}
+	if c > 0 {
+		inc.next, inc.nextKeys = av, make(map[string]bool)
+	}
+	inc.nextKeys[keyID(item, inc.keyAttributes)] = true
+}
+
+// compare returns -1, 0 or 1 if a is less than, equal to or greater than b, both have to be either strings or numbers,
+// otherwise they are not comparable.
+func compare(a *dynamodb.AttributeValue, b *dynamodb.AttributeValue) (int, bool) {
+	if a.S != nil && b.S != nil {
+		switch {
+		case *a.S < *b.S:
+			return -1, true
+		case *a.S > *b.S:
+			return 1, true
+		}
+		return 0, true
+	}
+	if a.N != nil && b.N != nil {
+		x, ok := new(big.Rat).SetString(*a.N)
+		y, ok2 := new(big.Rat).SetString(*b.N)
+		if !ok || !ok2 {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	}
+	return 0, false
+}
@@ -0,0 +1,297 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var byUpdatedAtDescription = &dynamodb.TableDescription{
+	KeySchema: ordersDescription.KeySchema,
+	GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+		{
+			IndexName: aws.String("byUpdatedAt"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("status"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				{AttributeName: aws.String("updatedAt"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+			},
+		},
+	},
+}
+
+func TestIncrementalApply(t *testing.T) {
+	watermark := &dynamodb.AttributeValue{S: aws.String("2020-05-16T10:00:00Z")}
+	tests := []struct {
+		name       string
+		watermark  *dynamodb.AttributeValue
+		index      string
+		qp         *QueryParams
+		wantQP     *QueryParams
+		wantFilter Filter
+		wantErr    bool
+	}{
+		{
+			name:   "first run",
+			qp:     &QueryParams{},
+			wantQP: &QueryParams{},
+		},
+		{
+			name:      "scan",
+			watermark: watermark,
+			qp:        &QueryParams{},
+			wantQP:    &QueryParams{},
+			wantFilter: Filter{
+				{Attribute: "updatedAt", Operator: filterGreaterOrEqual, Value: "2020-05-16T10:00:00Z", Type: "S"},
+			},
+		},
+		{
+			name:      "scan by number",
+			watermark: &dynamodb.AttributeValue{N: aws.String("1589623200")},
+			qp:        &QueryParams{},
+			wantQP:    &QueryParams{},
+			wantFilter: Filter{
+				{Attribute: "updatedAt", Operator: filterGreaterOrEqual, Value: "1589623200", Type: "N"},
+			},
+		},
+		{
+			name:      "index query",
+			watermark: watermark,
+			index:     "byUpdatedAt",
+			qp:        &QueryParams{Hash: "active"},
+			wantQP:    &QueryParams{Hash: "active", SortGe: "2020-05-16T10:00:00Z"},
+		},
+		{
+			name:      "table query",
+			watermark: watermark,
+			qp:        &QueryParams{Hash: "c1"},
+			wantQP:    &QueryParams{Hash: "c1"},
+			wantFilter: Filter{
+				{Attribute: "updatedAt", Operator: filterGreaterOrEqual, Value: "2020-05-16T10:00:00Z", Type: "S"},
+			},
+		},
+		{
+			// the index is only queried, so it would be the whole table scanned instead
+			name:      "index without hash",
+			watermark: watermark,
+			index:     "byUpdatedAt",
+			qp:        &QueryParams{},
+			wantErr:   true,
+		},
+		{
+			name:    "index without hash on first run",
+			index:   "byUpdatedAt",
+			qp:      &QueryParams{},
+			wantErr: true,
+		},
+		{
+			name:      "index query with sort condition",
+			watermark: watermark,
+			index:     "byUpdatedAt",
+			qp:        &QueryParams{Hash: "active", SortLt: "2020-06-01"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inc := &Incremental{Attribute: "updatedAt", Watermark: tt.watermark}
+			gotQP, gotFilter, err := inc.apply(byUpdatedAtDescription, tt.index, tt.qp, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(gotQP, tt.wantQP) {
+				t.Errorf("apply() query = %+v, want %+v", gotQP, tt.wantQP)
+			}
+			if !reflect.DeepEqual(gotFilter, tt.wantFilter) {
+				t.Errorf("apply() filter = %v, want %v", gotFilter, tt.wantFilter)
+			}
+		})
+	}
+}
+
+type incrementalScanDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	scans []*dynamodb.ScanInput
+}
+
+func (m *incrementalScanDynamoDBClient) ScanPages(
+	input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+
+	m.scans = append(m.scans, input)
+	fn(&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{order("c1", "1")}}, true)
+	return nil
+}
+
+func TestIncrementalScan(t *testing.T) {
+	inc := &Incremental{Attribute: "updatedAt", Watermark: &dynamodb.AttributeValue{S: aws.String("10:00:00")}}
+	qp, filter, err := inc.apply(byUpdatedAtDescription, "", &QueryParams{}, nil)
+	if err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	if !qp.isEmpty() {
+		t.Fatalf("apply() query = %+v, want scan", qp)
+	}
+	svc := &incrementalScanDynamoDBClient{}
+	e := newExporter("orderId", "", 0, SingleWriter(&recordingWriter{}))
+	if err := scanPages(svc, byUpdatedAtDescription, "orders", filter, e); err != nil {
+		t.Fatalf("scanPages() error = %v", err)
+	}
+	want := &dynamodb.ScanInput{
+		TableName:                 aws.String("orders"),
+		FilterExpression:          aws.String("#0 >= :0"),
+		ExpressionAttributeNames:  map[string]*string{"#0": aws.String("updatedAt")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":0": {S: aws.String("10:00:00")}},
+	}
+	if len(svc.scans) != 1 || !reflect.DeepEqual(svc.scans[0], want) {
+		t.Errorf("scanPages() scans = %v, want %v", svc.scans, want)
+	}
+}
+
+func TestIncrementalState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "orders-incremental.json")
+	inc, err := LoadIncremental(path, "orders", "updatedAt")
+	if err != nil {
+		t.Fatalf("LoadIncremental() error = %v", err)
+	}
+	if inc.Watermark != nil {
+		t.Errorf("LoadIncremental() watermark = %v, want nil", inc.Watermark)
+	}
+	if _, _, err := inc.apply(ordersDescription, "", &QueryParams{}, nil); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	for _, n := range []string{"9", "10", "2", ""} {
+		item := order("c1", "1")
+		if n != "" {
+			item["updatedAt"] = &dynamodb.AttributeValue{N: aws.String(n)}
+		}
+		inc.track(item)
+	}
+	if err := inc.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// the numbers are compared by their values, not as strings
+	inc, err = LoadIncremental(path, "orders", "updatedAt")
+	if err != nil {
+		t.Fatalf("LoadIncremental() error = %v", err)
+	}
+	if got := aws.StringValue(inc.Watermark.N); got != "10" || inc.Exported != 4 {
+		t.Errorf("LoadIncremental() watermark = %s, exported = %d, want 10 and 4", got, inc.Exported)
+	}
+	if _, _, err := inc.apply(ordersDescription, "", &QueryParams{}, nil); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	// the watermark is kept if nothing newer is exported
+	inc.track(order("c1", "2"))
+	if err := inc.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if inc, err = LoadIncremental(path, "orders", "updatedAt"); err != nil || aws.StringValue(inc.Watermark.N) != "10" {
+		t.Errorf("LoadIncremental() = %v, %v, want watermark 10", inc, err)
+	}
+	if _, err := LoadIncremental(path, "orders", "createdAt"); err == nil {
+		t.Errorf("LoadIncremental() by the other attribute error = nil, want error")
+	}
+}
+
+// TestIncrementalWatermarkKeys exports the items sharing the watermark value, i.e. written after the last run has read
+// the page, but within the same second as the watermark.
+func TestIncrementalWatermarkKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "orders-incremental.json")
+	updated := func(orderID string, updatedAt string) map[string]*dynamodb.AttributeValue {
+		item := order("c1", orderID)
+		item["updatedAt"] = &dynamodb.AttributeValue{S: aws.String(updatedAt)}
+		return item
+	}
+	export := func(items ...map[string]*dynamodb.AttributeValue) ([]string, *Incremental) {
+		inc, err := LoadIncremental(path, "orders", "updatedAt")
+		if err != nil {
+			t.Fatalf("LoadIncremental() error = %v", err)
+		}
+		_, filter, err := inc.apply(ordersDescription, "", &QueryParams{}, nil)
+		if err != nil {
+			t.Fatalf("apply() error = %v", err)
+		}
+		if inc.Watermark != nil && filter[0].Operator != filterGreaterOrEqual {
+			t.Errorf("apply() filter = %v, want %s", filter, filterGreaterOrEqual)
+		}
+		writer := &recordingWriter{}
+		e := newExporter("orderId", "", 0, SingleWriter(writer))
+		e.incremental = inc
+		for _, key := range e.writers.Initial() {
+			e.sink(key)
+		}
+		e.process(items, true)
+		if err := inc.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		exported := make([]string, 0, len(writer.records))
+		for _, record := range writer.records {
+			exported = append(exported, record[0])
+		}
+		return exported, inc
+	}
+	tests := []struct {
+		name          string
+		items         []map[string]*dynamodb.AttributeValue
+		want          []string
+		wantWatermark string
+		wantKeys      []string
+	}{
+		{
+			name:          "first run",
+			items:         []map[string]*dynamodb.AttributeValue{updated("1", "10:00:00"), updated("2", "10:00:01")},
+			want:          []string{"1", "2"},
+			wantWatermark: "10:00:01",
+			wantKeys:      []string{"c1" + keySeparator + "2"},
+		},
+		{
+			name:          "item written later at the watermark",
+			items:         []map[string]*dynamodb.AttributeValue{updated("2", "10:00:01"), updated("3", "10:00:01")},
+			want:          []string{"3"},
+			wantWatermark: "10:00:01",
+			wantKeys:      []string{"c1" + keySeparator + "2", "c1" + keySeparator + "3"},
+		},
+		{
+			name:          "nothing new",
+			items:         []map[string]*dynamodb.AttributeValue{updated("3", "10:00:01"), updated("2", "10:00:01")},
+			want:          []string{},
+			wantWatermark: "10:00:01",
+			wantKeys:      []string{"c1" + keySeparator + "2", "c1" + keySeparator + "3"},
+		},
+		{
+			name: "newer items",
+			items: []map[string]*dynamodb.AttributeValue{
+				updated("2", "10:00:01"), updated("4", "10:00:02"), updated("1", "10:00:02"),
+			},
+			want:          []string{"4", "1"},
+			wantWatermark: "10:00:02",
+			wantKeys:      []string{"c1" + keySeparator + "1", "c1" + keySeparator + "4"},
+		},
+	}
+	for _, tt := range tests {
+		got, inc := export(tt.items...)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: exported = %v, want %v", tt.name, got, tt.want)
+		}
+		if watermark := aws.StringValue(inc.Watermark.S); watermark != tt.wantWatermark {
+			t.Errorf("%s: watermark = %s, want %s", tt.name, watermark, tt.wantWatermark)
+		}
+		if !reflect.DeepEqual(inc.WatermarkKeys, tt.wantKeys) {
+			t.Errorf("%s: watermark keys = %v, want %v", tt.name, inc.WatermarkKeys, tt.wantKeys)
+		}
+	}
+}
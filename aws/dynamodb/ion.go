@@ -0,0 +1,343 @@
+package dynamodb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/zshamrock/dynocsv/output"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// ionVersionMarker starts each item written by the export to S3, i.e. $ion_1_0 {Item:{...}}.
+	ionVersionMarker = "$ion_1_0"
+	// The annotations of the lists the sets are written as by the export to S3, i.e. $dynamodb_SS::["a","b"].
+	ionStringSetAnnotation = "$dynamodb_SS"
+	ionNumberSetAnnotation = "$dynamodb_NS"
+	ionBinarySetAnnotation = "$dynamodb_BS"
+)
+
+// ionDelimiters end the bare tokens, i.e. the symbols and the numbers.
+const ionDelimiters = ",:[]{}\"'"
+
+// ionReader reads the subset of the Amazon Ion text the export to S3 writes the items as, converting the values into
+// the attribute values: the strings are S, the decimals (and the integers) are N, the blobs are B, the bools are BOOL,
+// null is NULL, the lists are L, or SS, NS and BS if annotated as the sets, and the structs are M. The rest of Ion
+// (i.e. the timestamps, the clobs, the long strings, the s-expressions, the comments, etc.) is never written by the
+// export, so it is reported as unsupported.
+type ionReader struct {
+	r *bufio.Reader
+}
+
+func newIonReader(r io.Reader) *ionReader {
+	return &ionReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next top level value skipping the version markers, or io.EOF if there are no more values.
+func (ir *ionReader) next() (*dynamodb.AttributeValue, error) {
+	for {
+		c, err := ir.peek()
+		if err != nil {
+			return nil, err
+		}
+		if c != '$' {
+			return ir.value()
+		}
+		token, err := ir.token()
+		if err != nil {
+			return nil, err
+		}
+		if token != ionVersionMarker {
+			return nil, fmt.Errorf("unsupported Ion top level value %q", token)
+		}
+	}
+}
+
+// value reads the value, the only annotations supported are the ones of the sets.
+func (ir *ionReader) value() (*dynamodb.AttributeValue, error) {
+	c, err := ir.peek()
+	if err != nil {
+		return nil, ir.unexpectedEOF(err)
+	}
+	switch c {
+	case '{':
+		_, _ = ir.r.ReadByte()
+		if next, err := ir.r.Peek(1); err == nil && next[0] == '{' {
+			_, _ = ir.r.ReadByte()
+			return ir.blob()
+		}
+		return ir.structure()
+	case '[':
+		_, _ = ir.r.ReadByte()
+		return ir.list()
+	case '"':
+		_, _ = ir.r.ReadByte()
+		s, err := ir.quoted('"')
+		return &dynamodb.AttributeValue{S: aws.String(s)}, err
+	}
+	token, err := ir.token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case "":
+		return nil, fmt.Errorf("unsupported Ion value starting with %q", c)
+	case "true", "false":
+		return &dynamodb.AttributeValue{BOOL: aws.Bool(token == "true")}, nil
+	case "null":
+		return &dynamodb.AttributeValue{NULL: aws.Bool(true)}, nil
+	case ionStringSetAnnotation, ionNumberSetAnnotation, ionBinarySetAnnotation:
+		return ir.set(token)
+	}
+	n, err := ionNumber(token)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.AttributeValue{N: aws.String(n)}, nil
+}
+
+// ionNumber converts the Ion decimal (or integer), i.e. 103., -0.50 or 1.5d-3, into the DynamoDB number.
+func ionNumber(token string) (string, error) {
+	mantissa, exponent := token, ""
+	if i := strings.IndexAny(token, "dD"); i != -1 {
+		mantissa, exponent = token[:i], "e"+token[i+1:]
+	}
+	n := strings.TrimSuffix(mantissa, ".") + exponent
+	if strings.ContainsAny(mantissa, "eE") || !output.IsNumber(n) {
+		return "", fmt.Errorf("unsupported Ion value %q, expected decimal", token)
+	}
+	return n, nil
+}
+
+// structure reads the struct fields after the opening brace, the field names are either the symbols (quoted or not)
+// or the strings.
+func (ir *ionReader) structure() (*dynamodb.AttributeValue, error) {
+	m := make(map[string]*dynamodb.AttributeValue)
+	for {
+		c, err := ir.peek()
+		if err != nil {
+			return nil, ir.unexpectedEOF(err)
+		}
+		if c == '}' {
+			_, _ = ir.r.ReadByte()
+			return &dynamodb.AttributeValue{M: m}, nil
+		}
+		var name string
+		if c == '"' || c == '\'' {
+			_, _ = ir.r.ReadByte()
+			name, err = ir.quoted(c)
+		} else {
+			name, err = ir.token()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c, err = ir.peek(); err != nil || c != ':' || name == "" {
+			return nil, fmt.Errorf("expected \":\" after Ion struct field %q", name)
+		}
+		_, _ = ir.r.ReadByte()
+		if m[name], err = ir.value(); err != nil {
+			return nil, err
+		}
+		if err := ir.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// list reads the list values after the opening bracket.
+func (ir *ionReader) list() (*dynamodb.AttributeValue, error) {
+	list := make([]*dynamodb.AttributeValue, 0)
+	for {
+		c, err := ir.peek()
+		if err != nil {
+			return nil, ir.unexpectedEOF(err)
+		}
+		if c == ']' {
+			_, _ = ir.r.ReadByte()
+			return &dynamodb.AttributeValue{L: list}, nil
+		}
+		av, err := ir.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, av)
+		if err := ir.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// set reads the list after the set annotation, all its values have to be of the set's type.
+func (ir *ionReader) set(annotation string) (*dynamodb.AttributeValue, error) {
+	if next, err := ir.r.Peek(2); err != nil || string(next) != "::" {
+		return nil, fmt.Errorf("expected \"::\" after Ion annotation %s", annotation)
+	}
+	_, _ = ir.r.Discard(2)
+	if c, err := ir.peek(); err != nil || c != '[' {
+		return nil, fmt.Errorf("expected list after Ion annotation %s", annotation)
+	}
+	av, err := ir.value()
+	if err != nil {
+		return nil, err
+	}
+	set := &dynamodb.AttributeValue{}
+	for _, v := range av.L {
+		switch {
+		case annotation == ionStringSetAnnotation && v.S != nil:
+			set.SS = append(set.SS, v.S)
+		case annotation == ionNumberSetAnnotation && v.N != nil:
+			set.NS = append(set.NS, v.N)
+		case annotation == ionBinarySetAnnotation && v.B != nil:
+			set.BS = append(set.BS, v.B)
+		default:
+			return nil, fmt.Errorf("unexpected %v in Ion %s set", v, annotation)
+		}
+	}
+	return set, nil
+}
+
+// blob reads the base64 encoded blob after the opening double braces.
+func (ir *ionReader) blob() (*dynamodb.AttributeValue, error) {
+	encoded, err := ir.r.ReadString('}')
+	if err != nil {
+		return nil, ir.unexpectedEOF(err)
+	}
+	if c, err := ir.r.ReadByte(); err != nil || c != '}' {
+		return nil, fmt.Errorf("expected \"}}\" after Ion blob")
+	}
+	encoded = strings.Join(strings.Fields(strings.TrimSuffix(encoded, "}")), "")
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ion blob: %v", err)
+	}
+	return &dynamodb.AttributeValue{B: data}, nil
+}
+
+// quoted reads the string (or the quoted symbol) after the opening quote.
+func (ir *ionReader) quoted(quote byte) (string, error) {
+	var b strings.Builder
+	for {
+		c, err := ir.r.ReadByte()
+		if err != nil {
+			return "", ir.unexpectedEOF(err)
+		}
+		switch c {
+		case quote:
+			return b.String(), nil
+		case '\\':
+			if err := ir.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// escape writes the character of the escape sequence after the backslash.
+func (ir *ionReader) escape(b *strings.Builder) error {
+	c, err := ir.r.ReadByte()
+	if err != nil {
+		return ir.unexpectedEOF(err)
+	}
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case '0':
+		b.WriteByte(0)
+	case 'a':
+		b.WriteByte('\a')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'v':
+		b.WriteByte('\v')
+	case '"', '\'', '\\', '/', '?':
+		b.WriteByte(c)
+	case 'x', 'u', 'U':
+		size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+		digits := make([]byte, size)
+		if _, err := io.ReadFull(ir.r, digits); err != nil {
+			return ir.unexpectedEOF(err)
+		}
+		r, err := strconv.ParseUint(string(digits), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return fmt.Errorf("invalid Ion escape \\%c%s", c, digits)
+		}
+		b.WriteRune(rune(r))
+	default:
+		return fmt.Errorf("invalid Ion escape \\%c", c)
+	}
+	return nil
+}
+
+// token reads the bare symbol or number.
+func (ir *ionReader) token() (string, error) {
+	var b bytes.Buffer
+	for {
+		c, err := ir.r.ReadByte()
+		if err == io.EOF {
+			return b.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if isIonSpace(c) || strings.IndexByte(ionDelimiters, c) != -1 {
+			return b.String(), ir.r.UnreadByte()
+		}
+		b.WriteByte(c)
+	}
+}
+
+// separator reads the comma between the values, or checks the closing bracket follows.
+func (ir *ionReader) separator(closing byte) error {
+	c, err := ir.peek()
+	if err != nil {
+		return ir.unexpectedEOF(err)
+	}
+	if c == ',' {
+		_, _ = ir.r.ReadByte()
+		return nil
+	}
+	if c != closing {
+		return fmt.Errorf("expected \",\" or %q in Ion value, but found %q", closing, c)
+	}
+	return nil
+}
+
+// peek skips the whitespaces, and returns the next byte without reading it.
+func (ir *ionReader) peek() (byte, error) {
+	for {
+		next, err := ir.r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !isIonSpace(next[0]) {
+			return next[0], nil
+		}
+		_, _ = ir.r.ReadByte()
+	}
+}
+
+func (ir *ionReader) unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return fmt.Errorf("unexpected end of Ion value")
+	}
+	return err
+}
+
+func isIonSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,187 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIonReader(t *testing.T) {
+	data := `$ion_1_0 {Item:{Authors:$dynamodb_SS::["Author1","Author2"],Id:103.,Price:1.5d2,
+Ratings:$dynamodb_NS::[4.,5.],Title:"Book \"103\"é",InPublication:false,Data:{{aGVsbG8=}},Notes:null,
+'quoted field':"string",
+Tags:[1.,"a",{nested:true}]}}
+$ion_1_0 {Item:{Id:104.,Codes:$dynamodb_BS::[{{AQ==}},{{Ag==}}],Empty:{},"string field":[]}}
+`
+	reader := newIonReader(strings.NewReader(data))
+	got, err := reader.next()
+	if err != nil {
+		t.Fatalf("next() error = %v", err)
+	}
+	want := &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+		"Item": {M: map[string]*dynamodb.AttributeValue{
+			"Authors":       {SS: aws.StringSlice([]string{"Author1", "Author2"})},
+			"Id":            {N: aws.String("103")},
+			"Price":         {N: aws.String("1.5e2")},
+			"Ratings":       {NS: aws.StringSlice([]string{"4", "5"})},
+			"Title":         {S: aws.String("Book \"103\"é")},
+			"InPublication": {BOOL: aws.Bool(false)},
+			"Data":          {B: []byte("hello")},
+			"Notes":         {NULL: aws.Bool(true)},
+			"quoted field":  {S: aws.String("string")},
+			"Tags": {L: []*dynamodb.AttributeValue{
+				{N: aws.String("1")},
+				{S: aws.String("a")},
+				{M: map[string]*dynamodb.AttributeValue{"nested": {BOOL: aws.Bool(true)}}},
+			}},
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("next() = %v, want %v", got, want)
+	}
+	got, err = reader.next()
+	if err != nil {
+		t.Fatalf("next() error = %v", err)
+	}
+	want = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{
+		"Item": {M: map[string]*dynamodb.AttributeValue{
+			"Id":           {N: aws.String("104")},
+			"Codes":        {BS: [][]byte{{1}, {2}}},
+			"Empty":        {M: map[string]*dynamodb.AttributeValue{}},
+			"string field": {L: []*dynamodb.AttributeValue{}},
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("next() = %v, want %v", got, want)
+	}
+	if _, err := reader.next(); err != io.EOF {
+		t.Errorf("next() error = %v, want EOF", err)
+	}
+}
+
+// TestIonReaderValues covers each of the value types the export to S3 writes the attributes as.
+func TestIonReaderValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  *dynamodb.AttributeValue
+	}{
+		{name: "S", value: `"Book 103"`, want: &dynamodb.AttributeValue{S: aws.String("Book 103")}},
+		{name: "S empty", value: `""`, want: &dynamodb.AttributeValue{S: aws.String("")}},
+		{name: "S escapes", value: `"a\"b\\c\/d\ne\tf\r\0"`,
+			want: &dynamodb.AttributeValue{S: aws.String("a\"b\\c/d\ne\tf\r\x00")}},
+		{name: "S unicode escapes", value: `"\x41é\U0001F600"`,
+			want: &dynamodb.AttributeValue{S: aws.String("Aé😀")}},
+		{name: "S utf-8", value: `"日本語"`, want: &dynamodb.AttributeValue{S: aws.String("日本語")}},
+		{name: "N integer decimal", value: `103.`, want: &dynamodb.AttributeValue{N: aws.String("103")}},
+		{name: "N negative decimal", value: `-0.50`, want: &dynamodb.AttributeValue{N: aws.String("-0.50")}},
+		{name: "N decimal exponent", value: `1.5d-3`, want: &dynamodb.AttributeValue{N: aws.String("1.5e-3")}},
+		{name: "N integer", value: `42`, want: &dynamodb.AttributeValue{N: aws.String("42")}},
+		{name: "N 38 digits", value: `12345678901234567890123456789012345678.`,
+			want: &dynamodb.AttributeValue{N: aws.String("12345678901234567890123456789012345678")}},
+		{name: "B", value: `{{aGVsbG8=}}`, want: &dynamodb.AttributeValue{B: []byte("hello")}},
+		{name: "B empty", value: `{{}}`, want: &dynamodb.AttributeValue{B: []byte{}}},
+		{name: "BOOL true", value: `true`, want: &dynamodb.AttributeValue{BOOL: aws.Bool(true)}},
+		{name: "BOOL false", value: `false`, want: &dynamodb.AttributeValue{BOOL: aws.Bool(false)}},
+		{name: "NULL", value: `null`, want: &dynamodb.AttributeValue{NULL: aws.Bool(true)}},
+		{name: "SS", value: `$dynamodb_SS::["a","b"]`,
+			want: &dynamodb.AttributeValue{SS: aws.StringSlice([]string{"a", "b"})}},
+		{name: "NS", value: `$dynamodb_NS::[1.,2.5]`,
+			want: &dynamodb.AttributeValue{NS: aws.StringSlice([]string{"1", "2.5"})}},
+		{name: "BS", value: `$dynamodb_BS::[{{AQ==}},{{Ag==}}]`,
+			want: &dynamodb.AttributeValue{BS: [][]byte{{1}, {2}}}},
+		{name: "L", value: `[1.,"a",null,[true]]`, want: &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{
+			{N: aws.String("1")},
+			{S: aws.String("a")},
+			{NULL: aws.Bool(true)},
+			{L: []*dynamodb.AttributeValue{{BOOL: aws.Bool(true)}}},
+		}}},
+		{name: "M", value: `{a:1.,'b c':"x",'it\'s':{},"d":[]}`, want: &dynamodb.AttributeValue{
+			M: map[string]*dynamodb.AttributeValue{
+				"a":    {N: aws.String("1")},
+				"b c":  {S: aws.String("x")},
+				"it's": {M: map[string]*dynamodb.AttributeValue{}},
+				"d":    {L: []*dynamodb.AttributeValue{}},
+			}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newIonReader(strings.NewReader(`{Item:{v:` + tt.value + `}}`)).next()
+			if err != nil {
+				t.Fatalf("next() error = %v", err)
+			}
+			if v := got.M["Item"].M["v"]; !reflect.DeepEqual(v, tt.want) {
+				t.Errorf("next() = %v, want %v", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestIonReaderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "unterminated struct", data: `{Item:{Id:103.`},
+		{name: "missing colon", data: `{Item:{Id 103.}}`},
+		{name: "invalid decimal", data: `{Item:{Id:1.2.3}}`},
+		{name: "invalid blob", data: `{Item:{Data:{{not base64}}}}`},
+		{name: "unterminated string", data: `{Item:{Title:"unterminated}}`},
+		{name: "missing comma", data: `{Item:[1. 2.]}`},
+		{name: "invalid escape", data: `{Item:{Title:"\q"}}`},
+		{name: "mixed set", data: `{Item:{Tags:$dynamodb_SS::["a",1.]}}`},
+		{name: "set without list", data: `{Item:{Tags:$dynamodb_SS::"a"}}`},
+		// the rest of Ion is never written by the export
+		{name: "other annotation", data: `{Item:{Id:units::103.}}`},
+		{name: "other top level symbol", data: `$ion_symbol_table {Item:{}}`},
+		{name: "float", data: `{Item:{Id:1e3}}`},
+		{name: "hex integer", data: `{Item:{Id:0x1F}}`},
+		{name: "timestamp", data: `{Item:{At:2020-05-16T10:00:00Z}}`},
+		{name: "typed null", data: `{Item:{Notes:null.string}}`},
+		{name: "symbol value", data: `{Item:{Status:active}}`},
+		{name: "long string", data: `{Item:{Title:'''long'''}}`},
+		{name: "clob", data: `{Item:{Data:{{"text"}}}}`},
+		{name: "s-expression", data: `{Item:{Expr:(a b)}}`},
+		{name: "comment", data: `{Item:{Id:103. // comment
+}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newIonReader(strings.NewReader(tt.data)).next(); err == nil || err == io.EOF {
+				t.Errorf("next() error = %v, want error", err)
+			}
+		})
+	}
+}
+
+func TestIonNumber(t *testing.T) {
+	tests := []struct {
+		token   string
+		want    string
+		wantErr bool
+	}{
+		{token: "103.", want: "103"},
+		{token: "-0.50", want: "-0.50"},
+		{token: "1.5d-3", want: "1.5e-3"},
+		{token: "1.5D+3", want: "1.5e+3"},
+		{token: "7", want: "7"},
+		{token: "1_000", wantErr: true},
+		{token: "2e10", wantErr: true},
+		{token: "-0x10", wantErr: true},
+		{token: "nan", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			got, err := ionNumber(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ionNumber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ionNumber() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package dynamodb
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/zshamrock/dynocsv/output"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	batchGetItemLimit      = 100
+	batchGetItemMaxRetries = 10
+	maxBackoff             = 5 * time.Second
+	keySeparator           = "\x00"
+)
+
+// batchGetItemBackoff is the delay before the first retry of the unprocessed keys, doubled on each next retry.
+var batchGetItemBackoff = 100 * time.Millisecond
+
+// Keys represents the full primary keys of the items to fetch, in the order the items are written. The keys of the
+// items which are not found are written into Missing if it is set.
+type Keys struct {
+	Attributes []string
+	Values     [][]string
+	Missing    output.Writer
+}
+
+// ReadKeys reads the keys from the CSV, where the header names the key attributes, and each next record is the key.
+func ReadKeys(r io.Reader) (*Keys, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("keys header is missing")
+	}
+	keys := &Keys{Attributes: records[0], Values: records[1:]}
+	for i := range keys.Attributes {
+		keys.Attributes[i] = strings.TrimSpace(keys.Attributes[i])
+	}
+	return keys, nil
+}
+
+// validate checks the keys attributes are exactly the table's primary key attributes.
+func (k *Keys) validate(desc *dynamodb.TableDescription) error {
+	required := keyAttributeNames(desc)
+	attributes := make(map[string]bool, len(k.Attributes))
+	for _, attribute := range k.Attributes {
+		attributes[attribute] = true
+	}
+	if len(attributes) != len(k.Attributes) || len(attributes) != len(required) {
+		return fmt.Errorf("keys attributes %v have to be the table's primary key attributes %v", k.Attributes, required)
+	}
+	for _, attribute := range required {
+		if !attributes[attribute] {
+			return fmt.Errorf("keys attributes %v have to be the table's primary key attributes %v", k.Attributes, required)
+		}
+	}
+	return nil
+}
+
+// keyAttributeNames returns the table's primary key attributes, the hash key first.
+func keyAttributeNames(desc *dynamodb.TableDescription) []string {
+	names := make([]string, 0, 2)
+	for _, key := range []*dynamodb.KeySchemaElement{findHashKey(desc.KeySchema), findRangeKey(desc.KeySchema)} {
+		if key != nil {
+			names = append(names, aws.StringValue(key.AttributeName))
+		}
+	}
+	return names
+}
+
+// attributeValues converts the key values into the attribute values of the types from the table's attribute
+// definitions, binary values are expected to be base64 encoded.
+func (k *Keys) attributeValues(
+	values []string, definitions map[string]string) (map[string]*dynamodb.AttributeValue, error) {
+
+	if len(values) != len(k.Attributes) {
+		return nil, fmt.Errorf("key %v doesn't match the keys attributes %v", values, k.Attributes)
+	}
+	key := make(map[string]*dynamodb.AttributeValue, len(values))
+	for i, attribute := range k.Attributes {
+		value := values[i]
+		switch definitions[attribute] {
+		case dynamodb.ScalarAttributeTypeN:
+			key[attribute] = &dynamodb.AttributeValue{N: aws.String(value)}
+		case dynamodb.ScalarAttributeTypeB:
+			b, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode %s binary key value %q: %v", attribute, value, err)
+			}
+			key[attribute] = &dynamodb.AttributeValue{B: b}
+		default:
+			key[attribute] = &dynamodb.AttributeValue{S: aws.String(value)}
+		}
+	}
+	return key, nil
+}
+
+// keyID identifies the item by its key attributes values, the numbers are normalized, so "1.0" requested matches "1"
+// returned.
+func keyID(item map[string]*dynamodb.AttributeValue, attributes []string) string {
+	values := make([]string, 0, len(attributes))
+	for _, attribute := range attributes {
+		av := item[attribute]
+		switch {
+		case av == nil:
+			values = append(values, "")
+		case av.N != nil:
+			value := aws.StringValue(av.N)
+			if r, ok := new(big.Rat).SetString(value); ok {
+				value = r.RatString()
+			}
+			values = append(values, value)
+		case av.B != nil:
+			values = append(values, base64.StdEncoding.EncodeToString(av.B))
+		default:
+			values = append(values, aws.StringValue(av.S))
+		}
+	}
+	return strings.Join(values, keySeparator)
+}
+
+// batchGetPages fetches the items by the keys using BatchGetItem in the chunks of 100 keys, retrying the unprocessed
+// keys with the exponential backoff, and processes the items of each chunk in the order of the keys. The duplicate keys
+// are fetched only once.
+func batchGetPages(
+	svc dynamodbiface.DynamoDBAPI, desc *dynamodb.TableDescription, table string, keys *Keys, e *exporter) error {
+
+	if err := keys.validate(desc); err != nil {
+		return err
+	}
+	if keys.Missing != nil {
+		if err := keys.Missing.WriteHeader(keys.Attributes); err != nil {
+			return fmt.Errorf("failed to write missing keys: %v", err)
+		}
+	}
+	definitions := definitionsMapping(desc.AttributeDefinitions)
+	seen := make(map[string]bool, len(keys.Values))
+	for start := 0; start < len(keys.Values); {
+		requested := make([]map[string]*dynamodb.AttributeValue, 0, batchGetItemLimit)
+		values := make([][]string, 0, batchGetItemLimit)
+		for ; start < len(keys.Values) && len(requested) < batchGetItemLimit; start++ {
+			key, err := keys.attributeValues(keys.Values[start], definitions)
+			if err != nil {
+				return err
+			}
+			id := keyID(key, keys.Attributes)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			requested = append(requested, key)
+			values = append(values, keys.Values[start])
+		}
+		if len(requested) == 0 {
+			break
+		}
+		fetched, err := batchGetItems(svc, table, requested, keys.Attributes)
+		if err != nil {
+			return err
+		}
+		items := make([]map[string]*dynamodb.AttributeValue, 0, len(fetched))
+		for i, key := range requested {
+			if item, ok := fetched[keyID(key, keys.Attributes)]; ok {
+				items = append(items, item)
+			} else if keys.Missing != nil {
+				if err := keys.Missing.Write(values[i]); err != nil {
+					return fmt.Errorf("failed to write missing keys: %v", err)
+				}
+			}
+		}
+		if e.process(items, false) {
+			break
+		}
+	}
+	if keys.Missing != nil {
+		if err := keys.Missing.Flush(); err != nil {
+			return fmt.Errorf("failed to write missing keys: %v", err)
+		}
+	}
+	e.flush(true)
+	return nil
+}
+
+// batchGetItems fetches the single chunk of the keys, and returns the found items by their key ids.
+func batchGetItems(
+	svc dynamodbiface.DynamoDBAPI,
+	table string,
+	keys []map[string]*dynamodb.AttributeValue,
+	attributes []string) (map[string]map[string]*dynamodb.AttributeValue, error) {
+
+	fetched := make(map[string]map[string]*dynamodb.AttributeValue, len(keys))
+	request := map[string]*dynamodb.KeysAndAttributes{table: {Keys: keys}}
+	backoff := batchGetItemBackoff
+	for retry := 0; len(request) != 0; retry++ {
+		if retry > 0 {
+			if retry > batchGetItemMaxRetries {
+				return nil, fmt.Errorf("failed to fetch %d keys of table %s after %d retries",
+					len(request[table].Keys), table, batchGetItemMaxRetries)
+			}
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		result, err := svc.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: request})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Responses[table] {
+			fetched[keyID(item, attributes)] = item
+		}
+		request = result.UnprocessedKeys
+	}
+	return fetched, nil
+}
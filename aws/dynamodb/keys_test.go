@@ -0,0 +1,163 @@
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadKeys(t *testing.T) {
+	got, err := ReadKeys(strings.NewReader("customerId, orderId\nc1,1\nc2,2\n"))
+	if err != nil {
+		t.Fatalf("ReadKeys() error = %v", err)
+	}
+	want := &Keys{Attributes: []string{"customerId", "orderId"}, Values: [][]string{{"c1", "1"}, {"c2", "2"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadKeys() = %v, want %v", got, want)
+	}
+	if _, err := ReadKeys(strings.NewReader("")); err == nil {
+		t.Errorf("ReadKeys() error = nil, want error for missing header")
+	}
+}
+
+func TestKeysValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		attributes []string
+		wantErr    bool
+	}{
+		{name: "hash and sort", attributes: []string{"orderId", "customerId"}},
+		{name: "hash only", attributes: []string{"customerId"}, wantErr: true},
+		{name: "duplicate", attributes: []string{"customerId", "customerId"}, wantErr: true},
+		{name: "non key", attributes: []string{"customerId", "status"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &Keys{Attributes: tt.attributes}
+			if err := keys.validate(ordersDescription); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	attributes := []string{"customerId", "orderId"}
+	requested := map[string]*dynamodb.AttributeValue{
+		"customerId": {S: aws.String("c1")},
+		"orderId":    {N: aws.String("1.50")},
+	}
+	returned := map[string]*dynamodb.AttributeValue{
+		"customerId": {S: aws.String("c1")},
+		"orderId":    {N: aws.String("1.5")},
+	}
+	if keyID(requested, attributes) != keyID(returned, attributes) {
+		t.Errorf("keyID() = %q, want %q", keyID(requested, attributes), keyID(returned, attributes))
+	}
+}
+
+type batchGetDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	calls int
+	keys  []int
+}
+
+// BatchGetItem returns the items with the odd order ids only, and leaves the last key of each first request
+// unprocessed.
+func (m *batchGetDynamoDBClient) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	m.calls++
+	keys := input.RequestItems["orders"].Keys
+	m.keys = append(m.keys, len(keys))
+	output := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]*dynamodb.AttributeValue{}}
+	if len(keys) > 1 {
+		output.UnprocessedKeys = map[string]*dynamodb.KeysAndAttributes{"orders": {Keys: keys[len(keys)-1:]}}
+		keys = keys[:len(keys)-1]
+	}
+	// return in the reverse order, as BatchGetItem doesn't keep the order of the keys
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := keys[i]
+		var id int
+		_, _ = fmt.Sscan(aws.StringValue(key["orderId"].N), &id)
+		if id%2 == 1 {
+			output.Responses["orders"] = append(output.Responses["orders"], map[string]*dynamodb.AttributeValue{
+				"customerId": key["customerId"],
+				"orderId":    {N: aws.String(fmt.Sprint(id))},
+				"total":      {N: aws.String("10")},
+			})
+		}
+	}
+	return output, nil
+}
+
+func TestBatchGetPages(t *testing.T) {
+	batchGetItemBackoff = time.Millisecond
+	values := make([][]string, 0)
+	for i := 1; i <= 150; i++ {
+		values = append(values, []string{"c1", fmt.Sprint(i)})
+	}
+	// duplicate key is fetched only once
+	values = append(values, []string{"c1", "1.0"})
+	missing := &recordingWriter{}
+	keys := &Keys{Attributes: []string{"customerId", "orderId"}, Values: values, Missing: missing}
+	writer := &recordingWriter{}
+	e := &exporter{
+		columns:        "orderId",
+		skipAttributes: map[string]bool{},
+		attributes:     []string{"orderId"},
+		attributesSet:  map[string]bool{"orderId": true},
+		writers:        SingleWriter(writer),
+		sinks:          make(map[string]*sink),
+	}
+	svc := &batchGetDynamoDBClient{}
+	if err := batchGetPages(svc, ordersDescription, "orders", keys, e); err != nil {
+		t.Fatalf("batchGetPages() error = %v", err)
+	}
+	if want := []int{100, 1, 50, 1}; !reflect.DeepEqual(svc.keys, want) {
+		t.Errorf("batchGetPages() requested keys = %v, want %v", svc.keys, want)
+	}
+	wantRecords, wantMissing := make([][]string, 0), make([][]string, 0)
+	for i := 1; i <= 150; i++ {
+		if i%2 == 1 {
+			wantRecords = append(wantRecords, []string{fmt.Sprint(i)})
+		} else {
+			wantMissing = append(wantMissing, []string{"c1", fmt.Sprint(i)})
+		}
+	}
+	if !reflect.DeepEqual(writer.records, wantRecords) {
+		t.Errorf("batchGetPages() records = %v, want %v", writer.records, wantRecords)
+	}
+	if !reflect.DeepEqual(missing.header, keys.Attributes) || !reflect.DeepEqual(missing.records, wantMissing) {
+		t.Errorf("batchGetPages() missing = %v, %v, want %v, %v",
+			missing.header, missing.records, keys.Attributes, wantMissing)
+	}
+}
+
+// failingWriter fails once the number of the records is written, i.e. if the disk is full.
+type failingWriter struct {
+	recordingWriter
+	after int
+}
+
+func (w *failingWriter) Write(record []string) error {
+	if len(w.records) == w.after {
+		return errors.New("no space left on device")
+	}
+	return w.recordingWriter.Write(record)
+}
+
+func TestBatchGetPagesMissingKeysError(t *testing.T) {
+	batchGetItemBackoff = time.Millisecond
+	values := [][]string{{"c1", "1"}, {"c1", "2"}, {"c1", "3"}, {"c1", "4"}}
+	keys := &Keys{Attributes: []string{"customerId", "orderId"}, Values: values, Missing: &failingWriter{after: 1}}
+	e := newExporter("orderId", "", 0, SingleWriter(&recordingWriter{}))
+	err := batchGetPages(&batchGetDynamoDBClient{}, ordersDescription, "orders", keys, e)
+	if err == nil || !strings.Contains(err.Error(), "no space left on device") {
+		t.Errorf("batchGetPages() error = %v, want missing keys write error", err)
+	}
+}
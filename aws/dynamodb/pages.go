@@ -0,0 +1,148 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"strings"
+	"sync"
+)
+
+// pageFunc processes the items of the page, and returns whether to continue with the next page.
+type pageFunc func(
+	items []map[string]*dynamodb.AttributeValue, lastEvaluatedKey map[string]*dynamodb.AttributeValue) bool
+
+// pagesFunc reads the pages starting after the key (or from the beginning if it is nil), calling fn for each page
+// until it returns false.
+type pagesFunc func(startKey map[string]*dynamodb.AttributeValue, fn pageFunc) error
+
+// selectPages returns the pages of each scan segment if the query is empty, or of the query for each hash otherwise.
+// If projection is set, only these attributes are read.
+func selectPages(
+	svc dynamodbiface.DynamoDBAPI,
+	desc *dynamodb.TableDescription,
+	table string,
+	index string,
+	qp *QueryParams,
+	filter Filter,
+	segments uint,
+	projection []string) []pagesFunc {
+
+	if qp.isEmpty() {
+		if segments == 0 {
+			segments = 1
+		}
+		pages := make([]pagesFunc, 0, segments)
+		for segment := uint(0); segment < segments; segment++ {
+			scan := dynamodb.ScanInput{TableName: aws.String(table)}
+			if index != "" {
+				scan.IndexName = aws.String(index)
+			}
+			if segments > 1 {
+				scan.Segment = aws.Int64(int64(segment))
+				scan.TotalSegments = aws.Int64(int64(segments))
+			}
+			if len(filter) != 0 {
+				expr := filter.filterExpression(desc.AttributeDefinitions)
+				scan.FilterExpression = expr.Filter()
+				scan.ExpressionAttributeNames = expr.Names()
+				scan.ExpressionAttributeValues = expr.Values()
+			}
+			if len(projection) != 0 {
+				scan.ProjectionExpression = projectionExpression(projection, &scan.ExpressionAttributeNames)
+			}
+			pages = append(pages, scanPagesFunc(svc, scan))
+		}
+		return pages
+	}
+	hashes := qp.Hashes
+	if len(hashes) == 0 {
+		hashes = []string{qp.Hash}
+	}
+	pages := make([]pagesFunc, 0, len(hashes))
+	for _, hash := range hashes {
+		query := queryInput(desc, table, index, qp.withHash(hash), filter)
+		if len(projection) != 0 {
+			query.ProjectionExpression = projectionExpression(projection, &query.ExpressionAttributeNames)
+		}
+		pages = append(pages, queryPagesFunc(svc, *query))
+	}
+	return pages
+}
+
+// projectionExpression returns the projection expression of the attributes, adding their placeholders into the names
+// (the ones built by the expression builder are "#0", "#1", etc., so they don't clash).
+func projectionExpression(attributes []string, names *map[string]*string) *string {
+	if *names == nil {
+		*names = make(map[string]*string, len(attributes))
+	}
+	placeholders := make([]string, 0, len(attributes))
+	for i, attribute := range attributes {
+		placeholder := fmt.Sprintf("#p%d", i)
+		(*names)[placeholder] = aws.String(attribute)
+		placeholders = append(placeholders, placeholder)
+	}
+	return aws.String(strings.Join(placeholders, ", "))
+}
+
+func scanPagesFunc(svc dynamodbiface.DynamoDBAPI, scan dynamodb.ScanInput) pagesFunc {
+	return func(startKey map[string]*dynamodb.AttributeValue, fn pageFunc) error {
+		scan.ExclusiveStartKey = startKey
+		return svc.ScanPages(&scan, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			return fn(page.Items, page.LastEvaluatedKey)
+		})
+	}
+}
+
+func queryPagesFunc(svc dynamodbiface.DynamoDBAPI, query dynamodb.QueryInput) pagesFunc {
+	return func(startKey map[string]*dynamodb.AttributeValue, fn pageFunc) error {
+		query.ExclusiveStartKey = startKey
+		return svc.QueryPages(&query, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+			return fn(page.Items, page.LastEvaluatedKey)
+		})
+	}
+}
+
+// runPages runs fn for each of the pages, at most concurrency at the same time, and returns the total number of the
+// items fn has written, and the first error if any.
+func runPages(pages []pagesFunc, concurrency uint, fn func(i int) (int64, error)) (int64, error) {
+	written := make([]int64, len(pages))
+	err := runConcurrently(len(pages), concurrency, func(i int) error {
+		var err error
+		written[i], err = fn(i)
+		return err
+	})
+	var total int64
+	for _, n := range written {
+		total += n
+	}
+	return total, err
+}
+
+// runConcurrently runs fn for each of 0..n-1, at most concurrency at the same time, waits for all of them to finish,
+// and returns the first error (in the order of i) if any.
+func runConcurrently(n int, concurrency uint, fn func(i int) error) error {
+	if concurrency == 0 {
+		concurrency = 1
+	}
+	running := make(chan struct{}, concurrency)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			running <- struct{}{}
+			defer func() { <-running }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
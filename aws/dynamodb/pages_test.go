@@ -0,0 +1,52 @@
+package dynamodb
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	done := make([]bool, 10)
+	err := runConcurrently(len(done), 3, func(i int) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		running--
+		done[i] = true
+		mu.Unlock()
+		if i == 7 || i == 4 {
+			return fmt.Errorf("%d failed", i)
+		}
+		return nil
+	})
+	// all are run even if some have failed, and the error is the first one in the order of i
+	if err == nil || err.Error() != "4 failed" {
+		t.Errorf("runConcurrently() error = %v, want 4 failed", err)
+	}
+	for i, d := range done {
+		if !d {
+			t.Errorf("runConcurrently() %d is not run", i)
+		}
+	}
+	if maxRunning > 3 {
+		t.Errorf("runConcurrently() running = %d, want at most 3", maxRunning)
+	}
+}
+
+func TestRunPages(t *testing.T) {
+	total, err := runPages(make([]pagesFunc, 3), 0, func(i int) (int64, error) {
+		return int64(i + 1), nil
+	})
+	if err != nil || total != 6 {
+		t.Errorf("runPages() = %d, %v, want 6, nil", total, err)
+	}
+}
@@ -0,0 +1,71 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"regexp"
+	"strings"
+)
+
+const parameterTypeSeparator = ":"
+
+// statementTablePattern matches the table (and the index) the statement reads from, i.e. FROM "orders"."byStatus".
+var statementTablePattern = regexp.MustCompile(`(?i)\bFROM\s+"([^"]+)"(?:\."([^"]+)")?`)
+
+// Statement represents the PartiQL statement, i.e. SELECT * FROM "orders" WHERE customerId = ?, with the values of its
+// parameters.
+type Statement struct {
+	Statement  string
+	Parameters []*dynamodb.AttributeValue
+}
+
+// ParseStatement parses the values of the statement parameters, the type is inferred from the value the same as by the
+// import, or is set explicitly as the prefix, i.e. "S:123" (one of S, N, B, BOOL, NULL, SS, NS, BS, L or M).
+func ParseStatement(statement string, parameters []string) (*Statement, error) {
+	if strings.TrimSpace(statement) == "" {
+		return nil, fmt.Errorf("statement is empty")
+	}
+	st := &Statement{Statement: statement}
+	for _, parameter := range parameters {
+		av := inferAttributeValue(parameter)
+		if i := strings.Index(parameter, parameterTypeSeparator); i != -1 && attributeTypes[parameter[:i]] {
+			var err error
+			av, err = toAttributeValue(parameter[i+len(parameterTypeSeparator):], parameter[:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter %q: %v", parameter, err)
+			}
+			if av == nil {
+				return nil, fmt.Errorf("invalid parameter %q: empty set", parameter)
+			}
+		}
+		st.Parameters = append(st.Parameters, av)
+	}
+	return st, nil
+}
+
+// StatementTable returns the table and the index (if any) the statement reads from, or the empty strings if they are
+// not found.
+func StatementTable(statement string) (string, string) {
+	match := statementTablePattern.FindStringSubmatch(statement)
+	if match == nil {
+		return "", ""
+	}
+	return match[1], match[2]
+}
+
+// statementPages runs the statement using ExecuteStatement, following NextToken until the last page or the limit.
+func statementPages(svc dynamodbiface.DynamoDBAPI, st *Statement, e *exporter) error {
+	input := &dynamodb.ExecuteStatementInput{Statement: aws.String(st.Statement), Parameters: st.Parameters}
+	for {
+		output, err := svc.ExecuteStatement(input)
+		if err != nil {
+			return fmt.Errorf("error executing statement %q %v", st.Statement, err)
+		}
+		if e.process(output.Items, output.NextToken == nil) {
+			return nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
@@ -0,0 +1,142 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"testing"
+)
+
+func TestParseStatement(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters []string
+		want       []*dynamodb.AttributeValue
+		wantErr    bool
+	}{
+		{name: "no parameters"},
+		{
+			name:       "inferred",
+			parameters: []string{"c1", "10", "true", "a:b"},
+			want: []*dynamodb.AttributeValue{
+				{S: aws.String("c1")},
+				{N: aws.String("10")},
+				{BOOL: aws.Bool(true)},
+				{S: aws.String("a:b")},
+			},
+		},
+		{
+			name:       "explicit type",
+			parameters: []string{"S:10", "N:1.5", "SS:[a,b]"},
+			want: []*dynamodb.AttributeValue{
+				{S: aws.String("10")},
+				{N: aws.String("1.5")},
+				{SS: aws.StringSlice([]string{"a", "b"})},
+			},
+		},
+		{name: "invalid number", parameters: []string{"N:abc"}, wantErr: true},
+		{name: "empty set", parameters: []string{"SS:[]"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStatement(`SELECT * FROM "orders"`, tt.parameters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStatement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got.Parameters, tt.want) {
+				t.Errorf("ParseStatement() parameters = %v, want %v", got.Parameters, tt.want)
+			}
+		})
+	}
+	if _, err := ParseStatement(" ", nil); err == nil {
+		t.Errorf("ParseStatement() error = nil, want error for empty statement")
+	}
+}
+
+func TestStatementTable(t *testing.T) {
+	tests := []struct {
+		statement string
+		table     string
+		index     string
+	}{
+		{statement: `SELECT * FROM "orders" WHERE customerId = ?`, table: "orders"},
+		{statement: `select total from "orders"."byStatus" where status = 'NEW'`, table: "orders", index: "byStatus"},
+		{statement: `SELECT * FROM orders`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.statement, func(t *testing.T) {
+			table, index := StatementTable(tt.statement)
+			if table != tt.table || index != tt.index {
+				t.Errorf("StatementTable() = %q, %q, want %q, %q", table, index, tt.table, tt.index)
+			}
+		})
+	}
+}
+
+type statementDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	inputs []dynamodb.ExecuteStatementInput
+}
+
+// ExecuteStatement returns the 3 pages with the single order each.
+func (m *statementDynamoDBClient) ExecuteStatement(
+	input *dynamodb.ExecuteStatementInput) (*dynamodb.ExecuteStatementOutput, error) {
+
+	m.inputs = append(m.inputs, *input)
+	output := &dynamodb.ExecuteStatementOutput{}
+	switch aws.StringValue(input.NextToken) {
+	case "":
+		output.Items = []map[string]*dynamodb.AttributeValue{order("c1", "1")}
+		output.NextToken = aws.String("2")
+	case "2":
+		output.Items = []map[string]*dynamodb.AttributeValue{order("c1", "2")}
+		output.NextToken = aws.String("3")
+	default:
+		output.Items = []map[string]*dynamodb.AttributeValue{order("c1", "3")}
+	}
+	return output, nil
+}
+
+func TestStatementPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   uint
+		records [][]string
+		calls   int
+	}{
+		{name: "all pages", records: [][]string{{"c1", "1"}, {"c1", "2"}, {"c1", "3"}}, calls: 3},
+		{name: "limit", limit: 2, records: [][]string{{"c1", "1"}, {"c1", "2"}}, calls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &recordingWriter{}
+			e := &exporter{
+				skipAttributes: map[string]bool{},
+				attributes:     []string{"customerId", "orderId"},
+				attributesSet:  map[string]bool{"customerId": true, "orderId": true},
+				limit:          tt.limit,
+				writers:        SingleWriter(writer),
+				sinks:          make(map[string]*sink),
+			}
+			e.sink("")
+			svc := &statementDynamoDBClient{}
+			st := &Statement{
+				Statement:  `SELECT * FROM "orders" WHERE customerId = ?`,
+				Parameters: []*dynamodb.AttributeValue{{S: aws.String("c1")}},
+			}
+			if err := statementPages(svc, st, e); err != nil {
+				t.Fatalf("statementPages() error = %v", err)
+			}
+			if !reflect.DeepEqual(writer.records, tt.records) {
+				t.Errorf("statementPages() records = %v, want %v", writer.records, tt.records)
+			}
+			if len(svc.inputs) != tt.calls {
+				t.Errorf("statementPages() calls = %d, want %d", len(svc.inputs), tt.calls)
+			}
+			if got := svc.inputs[0]; !reflect.DeepEqual(got.Parameters, st.Parameters) {
+				t.Errorf("statementPages() parameters = %v, want %v", got.Parameters, st.Parameters)
+			}
+		})
+	}
+}
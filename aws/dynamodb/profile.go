@@ -0,0 +1,246 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"github.com/zshamrock/dynocsv/hll"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+const (
+	profileExamples      = 3
+	profileExampleLength = 40
+)
+
+// TableProfile represents what the scanned items of the table contain, attribute by attribute.
+type TableProfile struct {
+	Table      string             `json:"table"`
+	Items      int64              `json:"items"`
+	Attributes []AttributeProfile `json:"attributes"`
+}
+
+// AttributeProfile represents the presence, types and values of the single attribute across the scanned items. The
+// lengths are of the values as they are exported into CSV, and the min/max values are set for the numbers only.
+type AttributeProfile struct {
+	Name      string           `json:"name"`
+	Count     int64            `json:"count"`
+	Presence  float64          `json:"presence"`
+	Types     map[string]int64 `json:"types"`
+	Distinct  uint64           `json:"distinct"`
+	MinLength int              `json:"minLength"`
+	MaxLength int              `json:"maxLength"`
+	MinValue  string           `json:"minValue,omitempty"`
+	MaxValue  string           `json:"maxValue,omitempty"`
+	Examples  []string         `json:"examples"`
+}
+
+type attributeProfiler struct {
+	count     int64
+	types     map[string]int64
+	sketch    *hll.Sketch
+	lengths   bool
+	minLength int
+	maxLength int
+	min       *big.Float
+	max       *big.Float
+	minValue  string
+	maxValue  string
+	examples  []string
+}
+
+// ProfileParams represents the items to profile: the scan (split into the segments run in parallel) or the query of
+// the table (or index), optionally filtered, and if Sample is set only the first number of the items.
+type ProfileParams struct {
+	Table    string
+	Index    string
+	Query    *QueryParams
+	Filter   Filter
+	Segments uint
+	Sample   uint
+}
+
+// profiler profiles the items of all the pages, it is safe to share it between the segments running concurrently.
+type profiler struct {
+	mu         sync.Mutex
+	items      int64
+	sample     uint
+	attributes map[string]*attributeProfiler
+}
+
+// ProfileTable reads the items the same way as the other commands do, and reports what each of the attributes
+// contains.
+func ProfileTable(sp *awssessions.SessionParams, pp *ProfileParams) (*TableProfile, error) {
+	return profileItems(dynamodb.New(awssessions.GetSession(sp)), pp)
+}
+
+func profileItems(svc dynamodbiface.DynamoDBAPI, pp *ProfileParams) (*TableProfile, error) {
+	var desc *dynamodb.TableDescription
+	if !pp.Query.isEmpty() || len(pp.Filter) != 0 {
+		desc = describe(svc, pp.Table)
+	}
+	p := &profiler{sample: pp.Sample, attributes: make(map[string]*attributeProfiler)}
+	pages := selectPages(svc, desc, pp.Table, pp.Index, pp.Query, pp.Filter, pp.Segments, nil)
+	_, err := runPages(pages, pp.Segments, func(i int) (int64, error) {
+		return 0, pages[i](nil,
+			func(items []map[string]*dynamodb.AttributeValue, _ map[string]*dynamodb.AttributeValue) bool {
+				return !p.process(items)
+			})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.profile(pp.Table), nil
+}
+
+// process profiles the items, and returns whether the sample size has been reached.
+func (p *profiler) process(items []map[string]*dynamodb.AttributeValue) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sample > 0 && p.items == int64(p.sample) {
+		return true
+	}
+	for _, item := range items {
+		p.items++
+		for k, av := range item {
+			ap, ok := p.attributes[k]
+			if !ok {
+				ap = &attributeProfiler{types: make(map[string]int64), sketch: hll.New()}
+				p.attributes[k] = ap
+			}
+			ap.add(av)
+		}
+		if p.sample > 0 && p.items == int64(p.sample) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ap *attributeProfiler) add(av *dynamodb.AttributeValue) {
+	ap.count++
+	attributeType := getType(av)
+	ap.types[attributeType]++
+	value, handled := getValue(av)
+	if !handled {
+		// the value is not exported, but it still counts as the distinct one by its type
+		ap.sketch.Add(attributeType)
+		return
+	}
+	ap.sketch.Add(value)
+	length := utf8.RuneCountInString(value)
+	if !ap.lengths || length < ap.minLength {
+		ap.minLength = length
+	}
+	if !ap.lengths || length > ap.maxLength {
+		ap.maxLength = length
+	}
+	ap.lengths = true
+	if av.N != nil {
+		// parse with the enough precision to keep all 38 digits DynamoDB numbers could have
+		n, _, err := big.ParseFloat(value, 10, 128, big.ToNearestEven)
+		if err == nil {
+			if ap.min == nil || n.Cmp(ap.min) < 0 {
+				ap.min, ap.minValue = n, value
+			}
+			if ap.max == nil || n.Cmp(ap.max) > 0 {
+				ap.max, ap.maxValue = n, value
+			}
+		}
+	}
+	if len(ap.examples) < profileExamples {
+		example := value
+		if utf8.RuneCountInString(example) > profileExampleLength {
+			example = string([]rune(example)[:profileExampleLength]) + "..."
+		}
+		for _, e := range ap.examples {
+			if e == example {
+				return
+			}
+		}
+		ap.examples = append(ap.examples, example)
+	}
+}
+
+// profile returns the attributes ordered by their presence (most present first), and then by their names.
+func (p *profiler) profile(table string) *TableProfile {
+	tp := &TableProfile{Table: table, Items: p.items, Attributes: make([]AttributeProfile, 0, len(p.attributes))}
+	for name, ap := range p.attributes {
+		tp.Attributes = append(tp.Attributes, AttributeProfile{
+			Name:      name,
+			Count:     ap.count,
+			Presence:  float64(ap.count) * 100 / float64(p.items),
+			Types:     ap.types,
+			Distinct:  ap.sketch.Estimate(),
+			MinLength: ap.minLength,
+			MaxLength: ap.maxLength,
+			MinValue:  ap.minValue,
+			MaxValue:  ap.maxValue,
+			Examples:  append([]string{}, ap.examples...),
+		})
+	}
+	sort.Slice(tp.Attributes, func(i, j int) bool {
+		if tp.Attributes[i].Count != tp.Attributes[j].Count {
+			return tp.Attributes[i].Count > tp.Attributes[j].Count
+		}
+		return tp.Attributes[i].Name < tp.Attributes[j].Name
+	})
+	return tp
+}
+
+// Print prints the human readable table profile.
+func (tp *TableProfile) Print(w io.Writer) error {
+	fmt.Fprintf(w, "Table: %s, items profiled: %d\n\n", tp.Table, tp.Items)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ATTRIBUTE\tPRESENCE\tTYPES\tDISTINCT\tLENGTH\tVALUE\tEXAMPLES")
+	for _, ap := range tp.Attributes {
+		types := make([]string, 0, len(ap.Types))
+		for t, count := range ap.Types {
+			types = append(types, fmt.Sprintf("%s:%d", t, count))
+		}
+		sort.Strings(types)
+		value := "-"
+		if ap.MinValue != "" {
+			value = fmt.Sprintf("%s..%s", ap.MinValue, ap.MaxValue)
+		}
+		fmt.Fprintf(tw, "%s\t%.1f%%\t%s\t~%d\t%d..%d\t%s\t%s\n",
+			ap.Name, ap.Presence, strings.Join(types, ","), ap.Distinct, ap.MinLength, ap.MaxLength, value,
+			strings.Join(ap.Examples, ", "))
+	}
+	return tw.Flush()
+}
+
+// getType returns the DynamoDB type of the attribute value, i.e. S, N, BOOL, etc.
+func getType(av *dynamodb.AttributeValue) string {
+	switch {
+	case av.BOOL != nil:
+		return "BOOL"
+	case av.N != nil:
+		return dynamodb.ScalarAttributeTypeN
+	case av.S != nil:
+		return dynamodb.ScalarAttributeTypeS
+	case av.M != nil:
+		return "M"
+	case av.SS != nil:
+		return "SS"
+	case av.NS != nil:
+		return "NS"
+	case av.L != nil:
+		return "L"
+	case av.B != nil:
+		return dynamodb.ScalarAttributeTypeB
+	case av.BS != nil:
+		return "BS"
+	case av.NULL != nil:
+		return "NULL"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,167 @@
+package dynamodb
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+)
+
+func TestProfilerProcess(t *testing.T) {
+	p := &profiler{attributes: make(map[string]*attributeProfiler)}
+	done := p.process([]map[string]*dynamodb.AttributeValue{
+		{"Id": {S: aws.String("a")}, "amount": {N: aws.String("10.5")}, "tags": {SS: []*string{aws.String("x")}}},
+		{"Id": {S: aws.String("bb")}, "amount": {N: aws.String("-3")}},
+		{"Id": {S: aws.String("ccc")}, "amount": {S: aws.String("n/a")}, "data": {B: []byte("x")}},
+		{"Id": {S: aws.String("bb")}, "amount": {N: aws.String("12345678901234567890123456789012345678")}},
+	})
+	if done {
+		t.Errorf("process() = %v, want %v", done, false)
+	}
+	want := &TableProfile{
+		Table: "t1",
+		Items: 4,
+		Attributes: []AttributeProfile{
+			{
+				Name:      "Id",
+				Count:     4,
+				Presence:  100,
+				Types:     map[string]int64{"S": 4},
+				Distinct:  3,
+				MinLength: 1,
+				MaxLength: 3,
+				Examples:  []string{"a", "bb", "ccc"},
+			},
+			{
+				Name:      "amount",
+				Count:     4,
+				Presence:  100,
+				Types:     map[string]int64{"N": 3, "S": 1},
+				Distinct:  4,
+				MinLength: 2,
+				MaxLength: 38,
+				MinValue:  "-3",
+				MaxValue:  "12345678901234567890123456789012345678",
+				Examples:  []string{"10.5", "-3", "n/a"},
+			},
+			{
+				Name:     "data",
+				Count:    1,
+				Presence: 25,
+				Types:    map[string]int64{"B": 1},
+				Distinct: 1,
+				Examples: []string{},
+			},
+			{
+				Name:      "tags",
+				Count:     1,
+				Presence:  25,
+				Types:     map[string]int64{"SS": 1},
+				Distinct:  1,
+				MinLength: 3,
+				MaxLength: 3,
+				Examples:  []string{"[x]"},
+			},
+		},
+	}
+	if got := p.profile("t1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("profile() = %+v, want %+v", got, want)
+	}
+}
+
+func TestProfilerProcessSample(t *testing.T) {
+	p := &profiler{sample: 2, attributes: make(map[string]*attributeProfiler)}
+	done := p.process([]map[string]*dynamodb.AttributeValue{
+		{"Id": {S: aws.String("a")}},
+		{"Id": {S: aws.String("b")}},
+		{"Id": {S: aws.String("c")}},
+	})
+	if !done || p.items != 2 {
+		t.Errorf("process() = %v, items %v, want %v, items %v", done, p.items, true, 2)
+	}
+}
+
+func TestProfileItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		segments  uint
+		sample    uint
+		wantItems int64
+		wantScans int
+	}{
+		{name: "scan", segments: 1, wantItems: 2, wantScans: 1},
+		{name: "parallel scan", segments: 3, wantItems: 6, wantScans: 3},
+		{name: "sample", segments: 3, sample: 3, wantItems: 3, wantScans: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &deleteDynamoDBClient{}
+			got, err := profileItems(svc, &ProfileParams{
+				Table:    "orders",
+				Query:    &QueryParams{},
+				Segments: tt.segments,
+				Sample:   tt.sample,
+			})
+			if err != nil {
+				t.Fatalf("profileItems() error = %v", err)
+			}
+			if got.Items != tt.wantItems {
+				t.Errorf("profileItems() items = %d, want %d", got.Items, tt.wantItems)
+			}
+			if len(svc.scans) != tt.wantScans {
+				t.Errorf("profileItems() scans = %d, want %d", len(svc.scans), tt.wantScans)
+			}
+		})
+	}
+}
+
+func TestTableProfilePrint(t *testing.T) {
+	tp := &TableProfile{
+		Table: "t1",
+		Items: 2,
+		Attributes: []AttributeProfile{
+			{
+				Name: "amount", Count: 1, Presence: 50, Types: map[string]int64{"S": 1, "N": 1}, Distinct: 2,
+				MinLength: 1, MaxLength: 3, MinValue: "1", MaxValue: "1", Examples: []string{"1", "n/a"},
+			},
+		},
+	}
+	var b bytes.Buffer
+	if err := tp.Print(&b); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	want := `Table: t1, items profiled: 2
+
+ATTRIBUTE  PRESENCE  TYPES    DISTINCT  LENGTH  VALUE  EXAMPLES
+amount     50.0%     N:1,S:1  ~2        1..3    1..1   1, n/a
+`
+	if got := b.String(); got != want {
+		t.Errorf("Print() = %v, want %v", got, want)
+	}
+}
+
+func TestGetType(t *testing.T) {
+	tests := []struct {
+		av   *dynamodb.AttributeValue
+		want string
+	}{
+		{av: &dynamodb.AttributeValue{BOOL: aws.Bool(true)}, want: "BOOL"},
+		{av: &dynamodb.AttributeValue{N: aws.String("1")}, want: "N"},
+		{av: &dynamodb.AttributeValue{S: aws.String("s")}, want: "S"},
+		{av: &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}, want: "M"},
+		{av: &dynamodb.AttributeValue{SS: []*string{}}, want: "SS"},
+		{av: &dynamodb.AttributeValue{NS: []*string{}}, want: "NS"},
+		{av: &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}, want: "L"},
+		{av: &dynamodb.AttributeValue{B: []byte{}}, want: "B"},
+		{av: &dynamodb.AttributeValue{BS: [][]byte{}}, want: "BS"},
+		{av: &dynamodb.AttributeValue{NULL: aws.Bool(true)}, want: "NULL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := getType(tt.av); got != tt.want {
+				t.Errorf("getType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"io"
+	"sort"
+)
+
+// ReadTableSchema reads the table schema as it is written by "describe --json" or "--with-schema".
+func ReadTableSchema(r io.Reader) (*TableSchema, error) {
+	schema := &TableSchema{}
+	if err := json.NewDecoder(r).Decode(schema); err != nil {
+		return nil, fmt.Errorf("failed to read table schema: %v", err)
+	}
+	if len(schema.KeySchema) == 0 {
+		return nil, fmt.Errorf("table schema has no key schema")
+	}
+	return schema, nil
+}
+
+// CreateTable creates the empty table (named as the schema if table is empty) with the key schema, indexes, billing
+// mode and stream settings of the schema, waits until it is active, and enables its time to live. If skipExisting is
+// set, the table which already exists is left as it is.
+func CreateTable(sp *awssessions.SessionParams, schema *TableSchema, table string, skipExisting bool) error {
+	return createTable(dynamodb.New(awssessions.GetSession(sp)), schema, table, skipExisting)
+}
+
+func createTable(svc dynamodbiface.DynamoDBAPI, schema *TableSchema, table string, skipExisting bool) error {
+	if table == "" {
+		table = schema.Name
+	}
+	_, err := svc.CreateTable(schema.createTableInput(table))
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && skipExisting && aerr.Code() == dynamodb.ErrCodeResourceInUseException {
+			return nil
+		}
+		return fmt.Errorf("error creating table %s %v", table, err)
+	}
+	if err := svc.WaitUntilTableExists(&dynamodb.DescribeTableInput{TableName: aws.String(table)}); err != nil {
+		return fmt.Errorf("error waiting for table %s to be created %v", table, err)
+	}
+	if ttl := schema.TTL; ttl != nil && ttl.Attribute != "" &&
+		(ttl.Status == dynamodb.TimeToLiveStatusEnabled || ttl.Status == dynamodb.TimeToLiveStatusEnabling) {
+		_, err := svc.UpdateTimeToLive(&dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String(table),
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+				AttributeName: aws.String(ttl.Attribute),
+				Enabled:       aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("error enabling table %s time to live %v", table, err)
+		}
+	}
+	return nil
+}
+
+func (s *TableSchema) createTableInput(table string) *dynamodb.CreateTableInput {
+	provisioned := s.BillingMode == dynamodb.BillingModeProvisioned
+	definitions := make(map[string]string)
+	input := &dynamodb.CreateTableInput{
+		TableName:   aws.String(table),
+		KeySchema:   keySchemaElements(s.KeySchema, definitions),
+		BillingMode: aws.String(s.BillingMode),
+	}
+	if provisioned {
+		input.ProvisionedThroughput = provisionedThroughput(s.Throughput, nil)
+	}
+	for _, index := range s.GlobalSecondaryIndexes {
+		gsi := &dynamodb.GlobalSecondaryIndex{
+			IndexName:  aws.String(index.Name),
+			KeySchema:  keySchemaElements(index.KeySchema, definitions),
+			Projection: index.Projection.projection(),
+		}
+		if provisioned {
+			gsi.ProvisionedThroughput = provisionedThroughput(index.Throughput, s.Throughput)
+		}
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, gsi)
+	}
+	for _, index := range s.LocalSecondaryIndexes {
+		input.LocalSecondaryIndexes = append(input.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndex{
+			IndexName:  aws.String(index.Name),
+			KeySchema:  keySchemaElements(index.KeySchema, definitions),
+			Projection: index.Projection.projection(),
+		})
+	}
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(name),
+			AttributeType: aws.String(definitions[name]),
+		})
+	}
+	if s.Stream != nil && s.Stream.Enabled {
+		input.StreamSpecification = &dynamodb.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: aws.String(s.Stream.ViewType),
+		}
+	}
+	return input
+}
+
+// keySchemaElements returns the key schema, and collects the key attributes types into the definitions.
+func keySchemaElements(keys []KeyAttribute, definitions map[string]string) []*dynamodb.KeySchemaElement {
+	elements := make([]*dynamodb.KeySchemaElement, 0, len(keys))
+	for _, key := range keys {
+		elements = append(elements, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(key.Name),
+			KeyType:       aws.String(key.KeyType),
+		})
+		definitions[key.Name] = key.AttributeType
+	}
+	return elements
+}
+
+func (p ProjectionSchema) projection() *dynamodb.Projection {
+	projection := &dynamodb.Projection{ProjectionType: aws.String(p.Type)}
+	if len(p.NonKeyAttributes) != 0 {
+		projection.NonKeyAttributes = aws.StringSlice(p.NonKeyAttributes)
+	}
+	return projection
+}
+
+// provisionedThroughput returns the throughput, or the fallback one if it is not set, or 1 RCU and 1 WCU if neither
+// is set.
+func provisionedThroughput(throughput *Throughput, fallback *Throughput) *dynamodb.ProvisionedThroughput {
+	if throughput == nil {
+		throughput = fallback
+	}
+	if throughput == nil {
+		throughput = &Throughput{ReadCapacityUnits: 1, WriteCapacityUnits: 1}
+	}
+	return &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(throughput.ReadCapacityUnits),
+		WriteCapacityUnits: aws.Int64(throughput.WriteCapacityUnits),
+	}
+}
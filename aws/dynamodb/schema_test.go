@@ -0,0 +1,155 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadTableSchema(t *testing.T) {
+	data, err := json.Marshal(ordersSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadTableSchema(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadTableSchema() error = %v", err)
+	}
+	// the empty non key attributes are omitted from JSON, so the schemas are compared as JSON
+	if gotData, _ := json.Marshal(got); !bytes.Equal(gotData, data) {
+		t.Errorf("ReadTableSchema() = %s, want %s", gotData, data)
+	}
+	if _, err := ReadTableSchema(strings.NewReader(`{"name": "orders"}`)); err == nil {
+		t.Errorf("ReadTableSchema() error = nil, want error for missing key schema")
+	}
+}
+
+func TestCreateTableInput(t *testing.T) {
+	got := ordersSchema.createTableInput("orders-copy")
+	want := &dynamodb.CreateTableInput{
+		TableName: aws.String("orders-copy"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("customerId"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String("orderId"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+			{AttributeName: aws.String("status"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("customerId"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String("orderId"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("byStatus"),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String("status"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				},
+				Projection: &dynamodb.Projection{
+					ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+					NonKeyAttributes: aws.StringSlice([]string{"total"}),
+				},
+			},
+		},
+		LocalSecondaryIndexes: []*dynamodb.LocalSecondaryIndex{
+			{
+				IndexName: aws.String("byStatusLocal"),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String("customerId"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+					{AttributeName: aws.String("status"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+				},
+				Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly)},
+			},
+		},
+		StreamSpecification: &dynamodb.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: aws.String(dynamodb.StreamViewTypeNewAndOldImages),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("createTableInput() = %v, want %v", got, want)
+	}
+}
+
+func TestCreateTableInputProvisioned(t *testing.T) {
+	schema := &TableSchema{
+		Name: "t1",
+		KeySchema: []KeyAttribute{
+			{Name: "Id", KeyType: dynamodb.KeyTypeHash, AttributeType: dynamodb.ScalarAttributeTypeS},
+		},
+		GlobalSecondaryIndexes: []IndexSchema{
+			{
+				Name: "byName",
+				KeySchema: []KeyAttribute{
+					{Name: "name", KeyType: dynamodb.KeyTypeHash, AttributeType: dynamodb.ScalarAttributeTypeS},
+				},
+				Projection: ProjectionSchema{Type: dynamodb.ProjectionTypeAll},
+			},
+		},
+		BillingMode: dynamodb.BillingModeProvisioned,
+		Throughput:  &Throughput{ReadCapacityUnits: 5, WriteCapacityUnits: 2},
+	}
+	got := schema.createTableInput("t1")
+	want := &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(5), WriteCapacityUnits: aws.Int64(2)}
+	if !reflect.DeepEqual(got.ProvisionedThroughput, want) {
+		t.Errorf("ProvisionedThroughput = %v, want %v", got.ProvisionedThroughput, want)
+	}
+	if !reflect.DeepEqual(got.GlobalSecondaryIndexes[0].ProvisionedThroughput, want) {
+		t.Errorf("GSI ProvisionedThroughput = %v, want %v", got.GlobalSecondaryIndexes[0].ProvisionedThroughput, want)
+	}
+}
+
+type createTableDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	exists bool
+	ttl    *dynamodb.UpdateTimeToLiveInput
+}
+
+func (m *createTableDynamoDBClient) CreateTable(
+	*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+
+	if m.exists {
+		return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "table already exists", nil)
+	}
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (m *createTableDynamoDBClient) WaitUntilTableExists(*dynamodb.DescribeTableInput) error {
+	return nil
+}
+
+func (m *createTableDynamoDBClient) UpdateTimeToLive(
+	input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+
+	m.ttl = input
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
+
+func TestCreateTable(t *testing.T) {
+	svc := &createTableDynamoDBClient{}
+	if err := createTable(svc, ordersSchema, "", false); err != nil {
+		t.Fatalf("createTable() error = %v", err)
+	}
+	want := &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String("orders"),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String("expiresAt"),
+			Enabled:       aws.Bool(true),
+		},
+	}
+	if !reflect.DeepEqual(svc.ttl, want) {
+		t.Errorf("createTable() time to live = %v, want %v", svc.ttl, want)
+	}
+	svc = &createTableDynamoDBClient{exists: true}
+	if err := createTable(svc, ordersSchema, "", false); err == nil {
+		t.Errorf("createTable() error = nil, want error for existing table")
+	}
+	if err := createTable(svc, ordersSchema, "", true); err != nil {
+		t.Errorf("createTable() skip existing error = %v", err)
+	}
+}
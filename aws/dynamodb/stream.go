@@ -0,0 +1,612 @@
+package dynamodb
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"github.com/zshamrock/dynocsv/output"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// TailTrimHorizon starts the tail from the oldest records kept by the stream (the last 24 hours).
+	TailTrimHorizon = "trim-horizon"
+	// TailLatest starts the tail from the records written after it has been started.
+	TailLatest = "latest"
+
+	// TailFormatCSV writes the records as CSV rows, the image attributes are the "new:" and "old:" prefixed columns.
+	TailFormatCSV = "csv"
+	// TailFormatJSONL writes the records as JSON objects one per line, the images are the plain JSON objects.
+	TailFormatJSONL = "jsonl"
+
+	eventNameColumn        = "eventName"
+	creationDateTimeColumn = "ApproximateCreationDateTime"
+	sequenceNumberColumn   = "SequenceNumber"
+	newImageColumnPrefix   = "new:"
+	oldImageColumnPrefix   = "old:"
+
+	getRecordsLimit = 1000
+)
+
+// TailStartPositions are the positions of the stream the tail could start from.
+var TailStartPositions = []string{TailTrimHorizon, TailLatest}
+
+// TailFormats are the formats the tail could write the records in.
+var TailFormats = []string{TailFormatCSV, TailFormatJSONL}
+
+// tailPollInterval is the delay before the next read of the shards once there were no new records.
+var tailPollInterval = time.Second
+
+// TailParams represents where the tail of the table's stream starts from and what it writes into the output. If
+// Checkpoint is set, the sequence numbers of the records written are saved into the file, and the tail is resumed from
+// them. If Filename is set, the CSV records are appended into it rather than into Output, continuing with the header
+// already written into it, and once the record has the attributes not in the header, the file is rolled over into the
+// new one with the extended header. The tail stops once Limit records are written, no new records are read for Idle
+// (0 means it runs until stopped), or Stop is closed.
+type TailParams struct {
+	Table      string
+	StartFrom  string
+	Format     string
+	Filename   string
+	Checkpoint string
+	Limit      uint
+	Idle       time.Duration
+	Output     io.Writer
+	Stop       <-chan struct{}
+}
+
+// TailResult represents the number of the records written, and the files the CSV records have been rolled over into
+// before the header was extended.
+type TailResult struct {
+	Records int64
+	Parts   []string
+}
+
+// TailStream follows the table's stream, and writes each record (its event name, creation time, keys, and the new and
+// old images) into the output, reading the shards in the order of their lineage (the parent before its children) and
+// discovering the new ones once the shards are closed.
+func TailStream(sp *awssessions.SessionParams, tp *TailParams) (*TailResult, error) {
+	sess := awssessions.GetSession(sp)
+	return tailStream(dynamodbstreams.New(sess), describe(dynamodb.New(sess), tp.Table), tp)
+}
+
+func tailStream(
+	streams dynamodbstreamsiface.DynamoDBStreamsAPI,
+	desc *dynamodb.TableDescription,
+	tp *TailParams) (*TailResult, error) {
+
+	arn := aws.StringValue(desc.LatestStreamArn)
+	if arn == "" {
+		return nil, fmt.Errorf("table %s has no stream enabled", tp.Table)
+	}
+	state, err := loadTailState(tp.Checkpoint, tp.Table, arn)
+	if err != nil {
+		return nil, err
+	}
+	var writer recordWriter = newJSONRecordWriter(tp.Output)
+	var rolling *output.RollingWriter
+	if tp.Format != TailFormatJSONL && tp.Filename != "" {
+		if rolling, err = output.NewRollingWriter(tp.Filename); err != nil {
+			return nil, err
+		}
+		writer = newCSVRecordWriter(rolling, rolling.Header(), desc.KeySchema, true)
+	} else if tp.Format != TailFormatJSONL {
+		writer = newCSVRecordWriter(output.NewCSVWriter(tp.Output), nil, desc.KeySchema, false)
+	}
+	t := &tailer{
+		streams:   streams,
+		tp:        tp,
+		arn:       arn,
+		state:     state,
+		writer:    writer,
+		iterators: make(map[string]*string),
+		fresh:     len(state.Shards) == 0,
+	}
+	err = t.run()
+	result := &TailResult{Records: t.records}
+	if rolling != nil {
+		if closeErr := rolling.Close(); err == nil {
+			err = closeErr
+		}
+		result.Parts = rolling.Parts()
+	}
+	return result, err
+}
+
+// tailShard is the progress of the single shard, SequenceNumber is the one of the last record written, and Done is set
+// once the closed shard is read till its end. Latest is set if the shard is read from its latest records rather than
+// from its beginning, so it is resumed the same way until any of its records is written.
+type tailShard struct {
+	SequenceNumber string `json:"sequenceNumber,omitempty"`
+	Latest         bool   `json:"latest,omitempty"`
+	Done           bool   `json:"done"`
+}
+
+// tailState is the progress of the tail saved into the checkpoint file (if set), so it could be resumed.
+type tailState struct {
+	path      string
+	Table     string                `json:"table"`
+	StreamARN string                `json:"streamArn"`
+	Shards    map[string]*tailShard `json:"shards"`
+}
+
+// loadTailState loads the tail progress from the file, or starts the new one if the file doesn't exist yet.
+func loadTailState(path string, table string, arn string) (*tailState, error) {
+	state := &tailState{path: path, Table: table, StreamARN: arn, Shards: make(map[string]*tailShard)}
+	if path == "" {
+		return state, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint %s: %v", path, err)
+	}
+	if state.Table != table || state.StreamARN != arn {
+		return nil, fmt.Errorf("checkpoint %s is of the table %s stream %s, but it is table %s stream %s", path,
+			state.Table, state.StreamARN, table, arn)
+	}
+	if state.Shards == nil {
+		state.Shards = make(map[string]*tailShard)
+	}
+	return state, nil
+}
+
+func (s *tailState) save() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJSONFile(s.path, s)
+}
+
+// tailer reads the records of the shards which are not done yet, and whose parents (if still kept by the stream) are
+// done, iterators are the shard iterators of the shards being read (nil until the iterator is got).
+type tailer struct {
+	streams    dynamodbstreamsiface.DynamoDBStreamsAPI
+	tp         *TailParams
+	arn        string
+	state      *tailState
+	writer     recordWriter
+	iterators  map[string]*string
+	fresh      bool
+	discovered bool
+	rediscover bool
+	records    int64
+}
+
+func (t *tailer) run() error {
+	idleSince := time.Now()
+	for {
+		if !t.discovered || t.rediscover || len(t.iterators) == 0 {
+			if err := t.discover(); err != nil {
+				return err
+			}
+		}
+		read := 0
+		for _, id := range t.shards() {
+			if t.stopped() {
+				return nil
+			}
+			n, err := t.read(id)
+			if err != nil {
+				return err
+			}
+			read += n
+			if t.limitReached() {
+				return nil
+			}
+		}
+		if read != 0 {
+			idleSince = time.Now()
+			continue
+		}
+		if t.tp.Idle > 0 && time.Since(idleSince) >= t.tp.Idle {
+			return nil
+		}
+		select {
+		case <-t.tp.Stop:
+			return nil
+		case <-time.After(tailPollInterval):
+		}
+	}
+}
+
+func (t *tailer) stopped() bool {
+	select {
+	case <-t.tp.Stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *tailer) limitReached() bool {
+	return t.tp.Limit != 0 && t.records >= int64(t.tp.Limit)
+}
+
+// shards returns the shards being read in the order of their ids.
+func (t *tailer) shards() []string {
+	ids := make([]string, 0, len(t.iterators))
+	for id := range t.iterators {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// discover lists the shards of the stream, and starts reading the ones which are not done yet, and whose parents are
+// done or already trimmed from the stream. On the first start (without the checkpoint) from the latest records the
+// closed shards are skipped, and the open ones are read from their latest records, while all the shards found later
+// are read from their beginning.
+func (t *tailer) discover() error {
+	shards, err := describeShards(t.streams, t.arn)
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]bool, len(shards))
+	for _, shard := range shards {
+		id := aws.StringValue(shard.ShardId)
+		listed[id] = true
+		if _, ok := t.state.Shards[id]; ok {
+			continue
+		}
+		s := &tailShard{}
+		if t.fresh && !t.discovered && t.tp.StartFrom == TailLatest {
+			closed := shard.SequenceNumberRange != nil && shard.SequenceNumberRange.EndingSequenceNumber != nil
+			s.Done, s.Latest = closed, !closed
+		}
+		t.state.Shards[id] = s
+	}
+	for _, shard := range shards {
+		id := aws.StringValue(shard.ShardId)
+		if _, reading := t.iterators[id]; t.state.Shards[id].Done || reading {
+			continue
+		}
+		if parent := aws.StringValue(shard.ParentShardId); listed[parent] && !t.state.Shards[parent].Done {
+			continue
+		}
+		t.iterators[id] = nil
+	}
+	// the done shards trimmed from the stream are never listed again
+	for id, s := range t.state.Shards {
+		if s.Done && !listed[id] {
+			delete(t.state.Shards, id)
+		}
+	}
+	t.discovered, t.rediscover = true, false
+	return t.state.save()
+}
+
+func describeShards(streams dynamodbstreamsiface.DynamoDBStreamsAPI, arn string) ([]*dynamodbstreams.Shard, error) {
+	input := &dynamodbstreams.DescribeStreamInput{StreamArn: aws.String(arn)}
+	shards := make([]*dynamodbstreams.Shard, 0)
+	for {
+		output, err := streams.DescribeStream(input)
+		if err != nil {
+			return nil, fmt.Errorf("error describing stream %s %v", arn, err)
+		}
+		shards = append(shards, output.StreamDescription.Shards...)
+		if output.StreamDescription.LastEvaluatedShardId == nil {
+			return shards, nil
+		}
+		input.ExclusiveStartShardId = output.StreamDescription.LastEvaluatedShardId
+	}
+}
+
+// read reads the next page of the shard's records, writes them, and saves the sequence number of the last one. Once the
+// closed shard is read till its end, it is done, and the shards are discovered again to find its children.
+func (t *tailer) read(id string) (int, error) {
+	iterator := t.iterators[id]
+	if iterator == nil {
+		var err error
+		if iterator, err = t.iterator(id); err != nil {
+			return 0, err
+		}
+	}
+	output, err := t.streams.GetRecords(
+		&dynamodbstreams.GetRecordsInput{ShardIterator: iterator, Limit: aws.Int64(getRecordsLimit)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodbstreams.ErrCodeExpiredIteratorException {
+		// the iterator expires in 15 minutes, the new one starts after the last record written
+		t.iterators[id] = nil
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading shard %s records %v", id, err)
+	}
+	s := t.state.Shards[id]
+	n := 0
+	var writeErr error
+	for _, record := range output.Records {
+		if t.limitReached() {
+			break
+		}
+		if writeErr = t.writer.write(record); writeErr != nil {
+			break
+		}
+		s.SequenceNumber = aws.StringValue(record.Dynamodb.SequenceNumber)
+		t.records++
+		n++
+	}
+	if err := t.writer.flush(); err != nil {
+		return n, err
+	}
+	if writeErr != nil {
+		// the records written before the failed one are kept, so the tail is resumed from the failed one
+		if err := t.state.save(); err != nil {
+			return n, err
+		}
+		return n, writeErr
+	}
+	if n == len(output.Records) && output.NextShardIterator == nil {
+		s.Done = true
+		delete(t.iterators, id)
+		t.rediscover = true
+	} else {
+		t.iterators[id] = output.NextShardIterator
+		if n == 0 {
+			return 0, nil
+		}
+	}
+	// the checkpoint is saved only after the records are flushed, so no record is lost once the tail is resumed
+	return n, t.state.save()
+}
+
+// iterator returns the shard iterator which starts after the last record written, or from the start position of the
+// shard. If the last record written is already trimmed from the stream, the shard is read from its oldest record.
+func (t *tailer) iterator(id string) (*string, error) {
+	input := &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         aws.String(t.arn),
+		ShardId:           aws.String(id),
+		ShardIteratorType: aws.String(dynamodbstreams.ShardIteratorTypeTrimHorizon),
+	}
+	s := t.state.Shards[id]
+	if s.SequenceNumber != "" {
+		input.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeAfterSequenceNumber)
+		input.SequenceNumber = aws.String(s.SequenceNumber)
+	} else if s.Latest {
+		input.ShardIteratorType = aws.String(dynamodbstreams.ShardIteratorTypeLatest)
+	}
+	output, err := t.streams.GetShardIterator(input)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodbstreams.ErrCodeTrimmedDataAccessException &&
+		s.SequenceNumber != "" {
+
+		s.SequenceNumber = ""
+		return t.iterator(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting shard %s iterator %v", id, err)
+	}
+	return output.ShardIterator, nil
+}
+
+// recordWriter writes the stream records into the output.
+type recordWriter interface {
+	write(record *dynamodbstreams.Record) error
+	flush() error
+}
+
+// csvRecordWriter writes the record's event name, creation time and sequence number, then its keys, and then its new
+// and old images as the "new:" and "old:" prefixed columns. Unless the header is already written into the output, it
+// is the table's key attributes followed by the attributes of the first record (in the alphabetical order), both as
+// the "new:" and "old:" columns. The attributes detected later extend the header, which the rolling writer rolls over
+// into the new file for, otherwise the record fails, as the header already written can't be changed.
+type csvRecordWriter struct {
+	writer        output.Writer
+	columns       []string
+	columnsSet    map[string]bool
+	headerWritten bool
+	rolling       bool
+}
+
+func newCSVRecordWriter(
+	writer output.Writer, header []string, keySchema []*dynamodb.KeySchemaElement, rolling bool) *csvRecordWriter {
+
+	w := &csvRecordWriter{
+		writer:        writer,
+		columnsSet:    make(map[string]bool),
+		headerWritten: len(header) != 0,
+		rolling:       rolling,
+	}
+	if len(header) == 0 {
+		header = []string{eventNameColumn, creationDateTimeColumn, sequenceNumberColumn}
+		for _, key := range []*dynamodb.KeySchemaElement{findHashKey(keySchema), findRangeKey(keySchema)} {
+			if key != nil {
+				header = append(header, aws.StringValue(key.AttributeName))
+			}
+		}
+	}
+	w.appendColumns(header)
+	return w
+}
+
+func (w *csvRecordWriter) appendColumns(columns []string) {
+	for _, column := range columns {
+		if !w.columnsSet[column] {
+			w.columnsSet[column] = true
+			w.columns = append(w.columns, column)
+		}
+	}
+}
+
+// newColumns returns the record's columns which are not in the header yet, the keys first, and then the attributes
+// of both images as both "new:" and "old:" columns, so the records modifying or removing the same attributes fit into
+// the header.
+func (w *csvRecordWriter) newColumns(record *dynamodbstreams.Record) []string {
+	attributes := itemColumns(record.Dynamodb.NewImage, "")
+	attributes = append(attributes, itemColumns(record.Dynamodb.OldImage, "")...)
+	columns := itemColumns(record.Dynamodb.Keys, "")
+	for _, prefix := range []string{newImageColumnPrefix, oldImageColumnPrefix} {
+		prefixed := make([]string, 0, len(attributes))
+		for _, attribute := range attributes {
+			prefixed = append(prefixed, prefix+attribute)
+		}
+		sort.Strings(prefixed)
+		columns = append(columns, prefixed...)
+	}
+	detected := make([]string, 0)
+	seen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if !w.columnsSet[column] && !seen[column] {
+			seen[column] = true
+			detected = append(detected, column)
+		}
+	}
+	return detected
+}
+
+func (w *csvRecordWriter) write(record *dynamodbstreams.Record) error {
+	keys, newImage, oldImage := record.Dynamodb.Keys, record.Dynamodb.NewImage, record.Dynamodb.OldImage
+	if columns := w.newColumns(record); !w.headerWritten || len(columns) != 0 {
+		if w.headerWritten && !w.rolling {
+			return fmt.Errorf("record %s has the attributes %s not in the CSV header already written, write into "+
+				"the file to roll it over into the new one with the extended header, or use the %s format",
+				aws.StringValue(record.Dynamodb.SequenceNumber), strings.Join(columns, ","), TailFormatJSONL)
+		}
+		w.appendColumns(columns)
+		w.headerWritten = true
+		if err := w.writer.WriteHeader(w.columns); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		var av *dynamodb.AttributeValue
+		switch {
+		case column == eventNameColumn:
+			row[i] = aws.StringValue(record.EventName)
+			continue
+		case column == creationDateTimeColumn:
+			if t := record.Dynamodb.ApproximateCreationDateTime; t != nil {
+				row[i] = t.UTC().Format(time.RFC3339)
+			}
+			continue
+		case column == sequenceNumberColumn:
+			row[i] = aws.StringValue(record.Dynamodb.SequenceNumber)
+			continue
+		case strings.HasPrefix(column, newImageColumnPrefix):
+			av = newImage[strings.TrimPrefix(column, newImageColumnPrefix)]
+		case strings.HasPrefix(column, oldImageColumnPrefix):
+			av = oldImage[strings.TrimPrefix(column, oldImageColumnPrefix)]
+		default:
+			av = keys[column]
+		}
+		if av != nil {
+			row[i], _ = getValue(av)
+		}
+	}
+	return w.writer.Write(row)
+}
+
+func (w *csvRecordWriter) flush() error {
+	return w.writer.Flush()
+}
+
+// itemColumns returns the prefixed item's attributes, which values could be written, in the alphabetical order.
+func itemColumns(item map[string]*dynamodb.AttributeValue, prefix string) []string {
+	columns := make([]string, 0, len(item))
+	for k, av := range item {
+		if _, handled := getValue(av); handled {
+			columns = append(columns, prefix+k)
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// jsonRecord is the record written as the single JSON line.
+type jsonRecord struct {
+	EventName                   string                 `json:"eventName"`
+	ApproximateCreationDateTime string                 `json:"ApproximateCreationDateTime,omitempty"`
+	SequenceNumber              string                 `json:"SequenceNumber"`
+	Keys                        map[string]interface{} `json:"Keys"`
+	NewImage                    map[string]interface{} `json:"NewImage,omitempty"`
+	OldImage                    map[string]interface{} `json:"OldImage,omitempty"`
+}
+
+type jsonRecordWriter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newJSONRecordWriter(w io.Writer) *jsonRecordWriter {
+	writer := bufio.NewWriter(w)
+	return &jsonRecordWriter{writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+func (w *jsonRecordWriter) write(record *dynamodbstreams.Record) error {
+	jr := jsonRecord{
+		EventName:      aws.StringValue(record.EventName),
+		SequenceNumber: aws.StringValue(record.Dynamodb.SequenceNumber),
+	}
+	if t := record.Dynamodb.ApproximateCreationDateTime; t != nil {
+		jr.ApproximateCreationDateTime = t.UTC().Format(time.RFC3339)
+	}
+	jr.Keys = jsonItem(record.Dynamodb.Keys)
+	jr.NewImage = jsonItem(record.Dynamodb.NewImage)
+	jr.OldImage = jsonItem(record.Dynamodb.OldImage)
+	return w.encoder.Encode(jr)
+}
+
+func (w *jsonRecordWriter) flush() error {
+	return w.writer.Flush()
+}
+
+// jsonItem converts the item into the plain JSON object, the numbers are written as they are (not as floats), and the
+// binary values are base64 encoded.
+func jsonItem(item map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	if item == nil {
+		return nil
+	}
+	converted := make(map[string]interface{}, len(item))
+	for k, av := range item {
+		converted[k] = jsonValue(av)
+	}
+	return converted
+}
+
+func jsonValue(av *dynamodb.AttributeValue) interface{} {
+	switch {
+	case av.S != nil:
+		return *av.S
+	case av.N != nil:
+		return json.Number(*av.N)
+	case av.B != nil:
+		return av.B
+	case av.BOOL != nil:
+		return *av.BOOL
+	case av.SS != nil:
+		return aws.StringValueSlice(av.SS)
+	case av.NS != nil:
+		numbers := make([]json.Number, len(av.NS))
+		for i, n := range av.NS {
+			numbers[i] = json.Number(*n)
+		}
+		return numbers
+	case av.BS != nil:
+		return av.BS
+	case av.L != nil:
+		values := make([]interface{}, len(av.L))
+		for i, v := range av.L {
+			values[i] = jsonValue(v)
+		}
+		return values
+	case av.M != nil:
+		return jsonItem(av.M)
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package dynamodb
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go/service/dynamodbstreams/dynamodbstreamsiface"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamShard struct {
+	id      string
+	parent  string
+	closed  bool
+	records []*dynamodbstreams.Record
+}
+
+// streamsClient serves the records of the shards in the pages of 2 records, the iterator is "<shard id>/<index of the
+// next record>", the shards are described in the pages of 1 shard.
+type streamsClient struct {
+	dynamodbstreamsiface.DynamoDBStreamsAPI
+	shards []*streamShard
+	// expire expires the first iterator of each shard
+	expire    bool
+	expired   map[string]bool
+	iterators []string
+}
+
+func (m *streamsClient) DescribeStream(
+	input *dynamodbstreams.DescribeStreamInput) (*dynamodbstreams.DescribeStreamOutput, error) {
+
+	i := 0
+	for input.ExclusiveStartShardId != nil && m.shards[i].id != *input.ExclusiveStartShardId {
+		i++
+	}
+	if input.ExclusiveStartShardId != nil {
+		i++
+	}
+	desc := &dynamodbstreams.StreamDescription{}
+	if i < len(m.shards) {
+		s := m.shards[i]
+		shard := &dynamodbstreams.Shard{
+			ShardId:             aws.String(s.id),
+			SequenceNumberRange: &dynamodbstreams.SequenceNumberRange{},
+		}
+		if s.parent != "" {
+			shard.ParentShardId = aws.String(s.parent)
+		}
+		if s.closed {
+			shard.SequenceNumberRange.EndingSequenceNumber = aws.String("999")
+		}
+		desc.Shards = []*dynamodbstreams.Shard{shard}
+		if i+1 < len(m.shards) {
+			desc.LastEvaluatedShardId = shard.ShardId
+		}
+	}
+	return &dynamodbstreams.DescribeStreamOutput{StreamDescription: desc}, nil
+}
+
+func (m *streamsClient) shard(id string) *streamShard {
+	for _, s := range m.shards {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *streamsClient) GetShardIterator(
+	input *dynamodbstreams.GetShardIteratorInput) (*dynamodbstreams.GetShardIteratorOutput, error) {
+
+	s := m.shard(*input.ShardId)
+	next := 0
+	switch *input.ShardIteratorType {
+	case dynamodbstreams.ShardIteratorTypeLatest:
+		next = len(s.records)
+	case dynamodbstreams.ShardIteratorTypeAfterSequenceNumber:
+		for next < len(s.records) && *s.records[next].Dynamodb.SequenceNumber != *input.SequenceNumber {
+			next++
+		}
+		next++
+	}
+	m.iterators = append(m.iterators, fmt.Sprintf("%s %s %d", *input.ShardId, *input.ShardIteratorType, next))
+	return &dynamodbstreams.GetShardIteratorOutput{ShardIterator: aws.String(fmt.Sprintf("%s/%d", s.id, next))}, nil
+}
+
+func (m *streamsClient) GetRecords(input *dynamodbstreams.GetRecordsInput) (*dynamodbstreams.GetRecordsOutput, error) {
+	parts := strings.Split(*input.ShardIterator, "/")
+	s := m.shard(parts[0])
+	if m.expire && !m.expired[s.id] {
+		m.expired[s.id] = true
+		return nil, awserr.New(dynamodbstreams.ErrCodeExpiredIteratorException, "iterator expired", nil)
+	}
+	next, _ := strconv.Atoi(parts[1])
+	end := next + 2
+	if end > len(s.records) {
+		end = len(s.records)
+	}
+	output := &dynamodbstreams.GetRecordsOutput{Records: s.records[next:end]}
+	if end < len(s.records) || !s.closed {
+		output.NextShardIterator = aws.String(fmt.Sprintf("%s/%d", s.id, end))
+	}
+	return output, nil
+}
+
+func streamRecord(
+	eventName string,
+	sequenceNumber string,
+	newImage map[string]*dynamodb.AttributeValue,
+	oldImage map[string]*dynamodb.AttributeValue) *dynamodbstreams.Record {
+
+	image := newImage
+	if image == nil {
+		image = oldImage
+	}
+	created := time.Date(2020, 5, 16, 10, 0, 0, 0, time.UTC)
+	return &dynamodbstreams.Record{
+		EventName: aws.String(eventName),
+		Dynamodb: &dynamodbstreams.StreamRecord{
+			ApproximateCreationDateTime: &created,
+			SequenceNumber:              aws.String(sequenceNumber),
+			Keys: map[string]*dynamodb.AttributeValue{
+				"customerId": image["customerId"],
+				"orderId":    image["orderId"],
+			},
+			NewImage: newImage,
+			OldImage: oldImage,
+		},
+	}
+}
+
+func withStatus(item map[string]*dynamodb.AttributeValue, status string) map[string]*dynamodb.AttributeValue {
+	item["status"] = &dynamodb.AttributeValue{S: aws.String(status)}
+	return item
+}
+
+// newStreamsClient returns the stream of the closed parent shard with 3 records, and its open child with 1 record.
+func newStreamsClient() *streamsClient {
+	return &streamsClient{
+		expired: make(map[string]bool),
+		shards: []*streamShard{
+			{id: "shard-2", parent: "shard-1", records: []*dynamodbstreams.Record{
+				streamRecord(dynamodbstreams.OperationTypeRemove, "400", nil, order("c1", "1")),
+			}},
+			{id: "shard-1", closed: true, records: []*dynamodbstreams.Record{
+				streamRecord(dynamodbstreams.OperationTypeInsert, "100", order("c1", "1"), nil),
+				streamRecord(dynamodbstreams.OperationTypeInsert, "200", order("c1", "2"), nil),
+				streamRecord(dynamodbstreams.OperationTypeModify, "300",
+					withStatus(order("c1", "1"), "shipped"), order("c1", "1")),
+			}},
+		},
+	}
+}
+
+func TestTailStream(t *testing.T) {
+	tailPollInterval = time.Millisecond
+	header := "eventName,ApproximateCreationDateTime,SequenceNumber,customerId,orderId," +
+		"new:customerId,new:orderId,old:customerId,old:orderId"
+	tests := []struct {
+		name      string
+		format    string
+		startFrom string
+		file      bool
+		written   string
+		expire    bool
+		want      string
+		wantParts map[string]string
+		wantErr   string
+		// wantCheckpoint is the sequence number of the last record of shard-1 saved into the checkpoint
+		wantCheckpoint string
+		records        int64
+	}{
+		{
+			name:   "csv rolled over",
+			format: TailFormatCSV,
+			file:   true,
+			// the status first seen by the third record extends the header, so the file is rolled over
+			want: header + ",new:status,old:status\n" +
+				"MODIFY,2020-05-16T10:00:00Z,300,c1,1,c1,1,c1,1,shipped,\n" +
+				"REMOVE,2020-05-16T10:00:00Z,400,c1,1,,,c1,1,,\n",
+			wantParts: map[string]string{
+				"orders-00001.csv": header + "\n" +
+					"INSERT,2020-05-16T10:00:00Z,100,c1,1,c1,1,,\n" +
+					"INSERT,2020-05-16T10:00:00Z,200,c1,2,c1,2,,\n",
+			},
+			records: 4,
+		},
+		{
+			name:   "csv appended",
+			format: TailFormatCSV,
+			file:   true,
+			written: "eventName,orderId,new:status,old:orderId,customerId,new:customerId,new:orderId,old:customerId," +
+				"old:status\nINSERT,0,,,c0,c0,0,,\n",
+			expire: true,
+			want: "eventName,orderId,new:status,old:orderId,customerId,new:customerId,new:orderId,old:customerId," +
+				"old:status\nINSERT,0,,,c0,c0,0,,\n" +
+				"INSERT,1,,,c1,c1,1,,\nINSERT,2,,,c1,c1,2,,\nMODIFY,1,shipped,1,c1,c1,1,c1,\nREMOVE,1,,1,c1,,,c1,\n",
+			wantParts: map[string]string{},
+			records:   4,
+		},
+		{
+			name:   "csv stdout",
+			format: TailFormatCSV,
+			// stdout can't be rolled over, so the attributes not in the header fail the tail
+			want: header + "\n" +
+				"INSERT,2020-05-16T10:00:00Z,100,c1,1,c1,1,,\n" +
+				"INSERT,2020-05-16T10:00:00Z,200,c1,2,c1,2,,\n",
+			wantErr: "record 300 has the attributes new:status,old:status not in the CSV header",
+			// the records written before the failed one are kept, so the tail is resumed from the failed one
+			wantCheckpoint: "200",
+			records:        2,
+		},
+		{
+			name:      "latest",
+			format:    TailFormatCSV,
+			startFrom: TailLatest,
+			want:      "",
+		},
+		{
+			name:   "jsonl",
+			format: TailFormatJSONL,
+			want: `{"eventName":"INSERT","ApproximateCreationDateTime":"2020-05-16T10:00:00Z","SequenceNumber":"100",` +
+				`"Keys":{"customerId":"c1","orderId":1},"NewImage":{"customerId":"c1","orderId":1}}` + "\n" +
+				`{"eventName":"INSERT","ApproximateCreationDateTime":"2020-05-16T10:00:00Z","SequenceNumber":"200",` +
+				`"Keys":{"customerId":"c1","orderId":2},"NewImage":{"customerId":"c1","orderId":2}}` + "\n" +
+				`{"eventName":"MODIFY","ApproximateCreationDateTime":"2020-05-16T10:00:00Z","SequenceNumber":"300",` +
+				`"Keys":{"customerId":"c1","orderId":1},` +
+				`"NewImage":{"customerId":"c1","orderId":1,"status":"shipped"},` +
+				`"OldImage":{"customerId":"c1","orderId":1}}` + "\n" +
+				`{"eventName":"REMOVE","ApproximateCreationDateTime":"2020-05-16T10:00:00Z","SequenceNumber":"400",` +
+				`"Keys":{"customerId":"c1","orderId":1},"OldImage":{"customerId":"c1","orderId":1}}` + "\n",
+			records: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "dynocsv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			streams := newStreamsClient()
+			streams.expire = tt.expire
+			var buf bytes.Buffer
+			tp := &TailParams{
+				Table:     "orders",
+				StartFrom: tt.startFrom,
+				Format:    tt.format,
+				Idle:      10 * time.Millisecond,
+				Output:    &buf,
+			}
+			if tt.wantCheckpoint != "" {
+				tp.Checkpoint = filepath.Join(dir, "checkpoint.json")
+			}
+			if tt.file {
+				tp.Filename = filepath.Join(dir, "orders.csv")
+				if err := ioutil.WriteFile(tp.Filename, []byte(tt.written), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := tailStream(streams, ordersDescription, tp)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("tailStream() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("tailStream() error = %v, want %s", err, tt.wantErr)
+			}
+			output := buf.String()
+			if tt.file {
+				data, _ := ioutil.ReadFile(tp.Filename)
+				output = string(data)
+			}
+			if output != tt.want {
+				t.Errorf("tailStream() output = %q, want %q", output, tt.want)
+			}
+			if got.Records != tt.records {
+				t.Errorf("tailStream() records = %d, want %d", got.Records, tt.records)
+			}
+			if tt.wantCheckpoint != "" {
+				state, err := loadTailState(tp.Checkpoint, "orders", aws.StringValue(ordersDescription.LatestStreamArn))
+				if err != nil {
+					t.Fatalf("loadTailState() error = %v", err)
+				}
+				if got := state.Shards["shard-1"].SequenceNumber; got != tt.wantCheckpoint {
+					t.Errorf("tailStream() checkpoint = %s, want %s", got, tt.wantCheckpoint)
+				}
+			}
+			if !tt.file {
+				return
+			}
+			parts := make(map[string]string, len(got.Parts))
+			for _, part := range got.Parts {
+				data, _ := ioutil.ReadFile(part)
+				parts[filepath.Base(part)] = string(data)
+			}
+			if !reflect.DeepEqual(parts, tt.wantParts) {
+				t.Errorf("tailStream() parts = %q, want %q", parts, tt.wantParts)
+			}
+		})
+	}
+}
+
+func TestTailStreamCheckpoint(t *testing.T) {
+	tailPollInterval = time.Millisecond
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+	tail := func(streams *streamsClient, limit uint) int64 {
+		return tailCheckpoint(t, streams, checkpoint, TailTrimHorizon, limit)
+	}
+	if got := tail(newStreamsClient(), 1); got != 1 {
+		t.Errorf("tailStream() records = %d, want 1", got)
+	}
+	// the tail is resumed after the last record written, and the child shard is read once its parent is done
+	streams := newStreamsClient()
+	if got := tail(streams, 0); got != 3 {
+		t.Errorf("tailStream() resumed records = %d, want 3", got)
+	}
+	want := []string{"shard-1 AFTER_SEQUENCE_NUMBER 1", "shard-2 TRIM_HORIZON 0"}
+	if !reflect.DeepEqual(streams.iterators, want) {
+		t.Errorf("tailStream() iterators = %v, want %v", streams.iterators, want)
+	}
+	if got := tail(newStreamsClient(), 0); got != 0 {
+		t.Errorf("tailStream() records = %d, want 0", got)
+	}
+	_, err = tailStream(streams, &dynamodb.TableDescription{LatestStreamArn: aws.String("arn:other")},
+		&TailParams{Table: "orders", Checkpoint: checkpoint})
+	if err == nil {
+		t.Errorf("tailStream() of the other stream error = nil, want error")
+	}
+	if _, err := tailStream(streams, &dynamodb.TableDescription{}, &TailParams{Table: "orders"}); err == nil {
+		t.Errorf("tailStream() without stream error = nil, want error")
+	}
+}
+
+func TestTailStreamCheckpointLatest(t *testing.T) {
+	tailPollInterval = time.Millisecond
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+	if got := tailCheckpoint(t, newStreamsClient(), checkpoint, TailLatest, 0); got != 0 {
+		t.Errorf("tailStream() records = %d, want 0", got)
+	}
+	// the open shard with no records written yet is resumed from its latest records again, not from its beginning
+	streams := newStreamsClient()
+	if got := tailCheckpoint(t, streams, checkpoint, TailLatest, 0); got != 0 {
+		t.Errorf("tailStream() resumed records = %d, want 0", got)
+	}
+	want := []string{"shard-2 LATEST 1"}
+	if !reflect.DeepEqual(streams.iterators, want) {
+		t.Errorf("tailStream() iterators = %v, want %v", streams.iterators, want)
+	}
+}
+
+func tailCheckpoint(t *testing.T, streams *streamsClient, checkpoint string, startFrom string, limit uint) int64 {
+	got, err := tailStream(streams, ordersDescription, &TailParams{
+		Table:      "orders",
+		StartFrom:  startFrom,
+		Format:     TailFormatJSONL,
+		Checkpoint: checkpoint,
+		Limit:      limit,
+		Idle:       10 * time.Millisecond,
+		Output:     ioutil.Discard,
+	})
+	if err != nil {
+		t.Fatalf("tailStream() error = %v", err)
+	}
+	return got.Records
+}
@@ -0,0 +1,96 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SortFormatISO formats the time range as ISO-8601 timestamps in UTC, i.e. "2020-05-01T10:00:00Z".
+	SortFormatISO = "iso"
+	// SortFormatEpoch formats the time range as the number of seconds since the Unix epoch.
+	SortFormatEpoch = "epoch"
+	// SortFormatEpochMs formats the time range as the number of milliseconds since the Unix epoch.
+	SortFormatEpochMs = "epoch-ms"
+
+	isoFormat = "2006-01-02T15:04:05Z"
+	daySuffix = "d"
+)
+
+// SortFormats are all the supported formats of the sort key timestamps.
+var SortFormats = []string{SortFormatISO, SortFormatEpoch, SortFormatEpochMs}
+
+// timeLayouts are the absolute time layouts accepted by ParseTime, the ones without the time zone are in UTC.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// ParseTime parses either the duration relative to now, i.e. "24h", "30m" or "7d" (days), or the absolute time, i.e.
+// "2020-05-01" or "2020-05-01T10:00:00Z".
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(value, daySuffix) {
+		if days, err := strconv.ParseUint(strings.TrimSuffix(value, daySuffix), 10, 32); err == nil {
+			return now.AddDate(0, 0, -int(days)), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected duration (i.e. 24h or 7d) or date (i.e. 2020-05-01 "+
+		"or 2020-05-01T10:00:00Z)", value)
+}
+
+func (qp *QueryParams) hasTimeRange() bool {
+	return qp.Since != nil || qp.Until != nil
+}
+
+// withTimeRange returns the copy of the query params with the time range translated into the corresponding sort
+// condition, formatted by the sort format, or if it is not set, as epoch for the number sort key and ISO-8601 otherwise.
+func (qp *QueryParams) withTimeRange(attributeType string) *QueryParams {
+	if !qp.hasTimeRange() {
+		return qp
+	}
+	format := qp.SortFormat
+	if format == "" {
+		format = SortFormatISO
+		if attributeType == dynamodb.ScalarAttributeTypeN {
+			format = SortFormatEpoch
+		}
+	}
+	params := *qp
+	switch {
+	case qp.Since != nil && qp.Until != nil:
+		params.SortBetween = []string{formatTime(*qp.Since, format), formatTime(*qp.Until, format)}
+	case qp.Since != nil:
+		params.SortGe = formatTime(*qp.Since, format)
+	default:
+		params.SortLe = formatTime(*qp.Until, format)
+	}
+	params.Since, params.Until = nil, nil
+	return &params
+}
+
+func formatTime(t time.Time, format string) string {
+	switch format {
+	case SortFormatISO:
+		return t.UTC().Format(isoFormat)
+	case SortFormatEpoch:
+		return strconv.FormatInt(t.Unix(), 10)
+	case SortFormatEpochMs:
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	}
+	log.Panicf("unsupported sort format %s", format)
+	return ""
+}
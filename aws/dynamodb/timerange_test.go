@@ -0,0 +1,81 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2020, 5, 16, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "24h", want: time.Date(2020, 5, 15, 12, 30, 0, 0, time.UTC)},
+		{value: "90m", want: time.Date(2020, 5, 16, 11, 0, 0, 0, time.UTC)},
+		{value: "7d", want: time.Date(2020, 5, 9, 12, 30, 0, 0, time.UTC)},
+		{value: "2020-05-01", want: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2020-05-01T10:00", want: time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2020-05-01T10:00:05", want: time.Date(2020, 5, 1, 10, 0, 5, 0, time.UTC)},
+		{value: "2020-05-01T10:00:00+02:00", want: time.Date(2020, 5, 1, 8, 0, 0, 0, time.UTC)},
+		{value: "yesterday", wantErr: true},
+		{value: "d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTime() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryParamsWithTimeRange(t *testing.T) {
+	since := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2020, 5, 2, 0, 0, 0, 500000000, time.UTC)
+	tests := []struct {
+		name          string
+		qp            *QueryParams
+		attributeType string
+		want          *QueryParams
+	}{
+		{
+			name:          "since and until/S",
+			qp:            &QueryParams{Hash: "h", Since: &since, Until: &until},
+			attributeType: dynamodb.ScalarAttributeTypeS,
+			want:          &QueryParams{Hash: "h", SortBetween: []string{"2020-05-01T00:00:00Z", "2020-05-02T00:00:00Z"}},
+		},
+		{
+			name:          "since/N",
+			qp:            &QueryParams{Hash: "h", Since: &since},
+			attributeType: dynamodb.ScalarAttributeTypeN,
+			want:          &QueryParams{Hash: "h", SortGe: "1588291200"},
+		},
+		{
+			name:          "until/epoch-ms",
+			qp:            &QueryParams{Hash: "h", Until: &until, SortFormat: SortFormatEpochMs},
+			attributeType: dynamodb.ScalarAttributeTypeS,
+			want:          &QueryParams{Hash: "h", SortLe: "1588377600500", SortFormat: SortFormatEpochMs},
+		},
+		{
+			name: "no time range",
+			qp:   &QueryParams{Hash: "h", Sort: "s"},
+			want: &QueryParams{Hash: "h", Sort: "s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.qp.withTimeRange(tt.attributeType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withTimeRange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package dynamodb
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"github.com/zshamrock/dynocsv/output"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	updateChunkSize = 100
+	// conditionColumnPrefix marks the column of the value the attribute has to have for the item to be updated, i.e.
+	// "if:version", the empty value means the attribute has to be missing.
+	conditionColumnPrefix = "if:"
+)
+
+// The results of the rows written into the update report.
+const (
+	UpdateResultUpdated         = "updated"
+	UpdateResultConditionFailed = "condition_failed"
+	UpdateResultSkipped         = "skipped"
+	UpdateResultError           = "error"
+)
+
+// UpdateParams represents the update of the items of the table by the CSV patch. The values are converted into the
+// attribute types set by Types, or the key attributes types, or the types inferred from the values the same as by the
+// import. The empty values are not updated, or removed if RemoveEmpty is set. Each item is updated only if it matches
+// the Condition (if it is set) and the "if:" columns, and only if it already exists unless Upsert is set. At most Rate
+// items are updated per second (0 means no limit) by Concurrency updates run in parallel. The result of each row is
+// written into Report if it is set.
+type UpdateParams struct {
+	Table       string
+	Types       map[string]string
+	Condition   Filter
+	RemoveEmpty bool
+	Upsert      bool
+	Rate        float64
+	Concurrency uint
+	Report      output.Writer
+}
+
+// UpdateResult is the number of the rows per result.
+type UpdateResult struct {
+	Updated         int64
+	ConditionFailed int64
+	Skipped         int64
+	Failed          int64
+}
+
+// updateColumn is the CSV column, either the attribute to update or the condition on the attribute.
+type updateColumn struct {
+	attribute string
+	condition bool
+	key       bool
+}
+
+// updateRow is the row of the patch with its result.
+type updateRow struct {
+	line    int
+	record  []string
+	result  string
+	message string
+}
+
+// attributeValue is the attribute value passed into the expression as it is.
+type attributeValue struct {
+	av *dynamodb.AttributeValue
+}
+
+func (v attributeValue) MarshalDynamoDBAttributeValue(av *dynamodb.AttributeValue) error {
+	*av = *v.av
+	return nil
+}
+
+// UpdateFromCSV updates the items of the table using UpdateItem, where the key columns of the CSV identify the item,
+// and the other columns are the attributes to set (or remove).
+func UpdateFromCSV(sp *awssessions.SessionParams, r io.Reader, up *UpdateParams) (*UpdateResult, error) {
+	svc := dynamodb.New(awssessions.GetSession(sp))
+	return updateItems(svc, describe(svc, up.Table), r, up)
+}
+
+func updateItems(
+	svc dynamodbiface.DynamoDBAPI,
+	desc *dynamodb.TableDescription,
+	r io.Reader,
+	up *UpdateParams) (*UpdateResult, error) {
+
+	reader := csv.NewReader(r)
+	// the rows of the wrong length are reported as errors, not to stop the whole update
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("header is missing")
+	}
+	if err != nil {
+		return nil, err
+	}
+	keyAttributes := keyAttributeNames(desc)
+	columns, err := updateColumns(header, keyAttributes)
+	if err != nil {
+		return nil, err
+	}
+	keyColumns := make([]int, 0, len(keyAttributes))
+	for _, attribute := range keyAttributes {
+		for i, column := range columns {
+			if column.key && column.attribute == attribute {
+				keyColumns = append(keyColumns, i)
+			}
+		}
+	}
+	if up.Report != nil {
+		header := append(append([]string{"line"}, keyAttributes...), "result", "message")
+		if err := up.Report.WriteHeader(header); err != nil {
+			return nil, err
+		}
+	}
+	u := &updater{
+		svc:           svc,
+		up:            up,
+		columns:       columns,
+		keyColumns:    keyColumns,
+		definitions:   definitionsMapping(desc.AttributeDefinitions),
+		hashAttribute: keyAttributes[0],
+		limiter:       &rateLimiter{rate: up.Rate},
+	}
+	result := &UpdateResult{}
+	rows := make([]*updateRow, 0, updateChunkSize)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+		rows = append(rows, &updateRow{line: line, record: record})
+		if len(rows) == updateChunkSize {
+			if err := u.updateRows(rows, result); err != nil {
+				return result, err
+			}
+			rows = rows[:0]
+		}
+	}
+	err = u.updateRows(rows, result)
+	return result, err
+}
+
+// updateColumns parses the header, which has to have all the table's primary key attributes.
+func updateColumns(header []string, keyAttributes []string) ([]updateColumn, error) {
+	keys := make(map[string]bool, len(keyAttributes))
+	for _, attribute := range keyAttributes {
+		keys[attribute] = true
+	}
+	columns := make([]updateColumn, 0, len(header))
+	seen := make(map[string]bool, len(header))
+	for _, name := range header {
+		name = strings.TrimSpace(name)
+		column := updateColumn{attribute: name}
+		if strings.HasPrefix(name, conditionColumnPrefix) {
+			column = updateColumn{attribute: strings.TrimPrefix(name, conditionColumnPrefix), condition: true}
+		}
+		if column.attribute == "" {
+			return nil, fmt.Errorf("header %v has the empty column name", header)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("header %v has the duplicate column %s", header, name)
+		}
+		seen[name] = true
+		column.key = !column.condition && keys[column.attribute]
+		columns = append(columns, column)
+	}
+	for _, attribute := range keyAttributes {
+		if !seen[attribute] {
+			return nil, fmt.Errorf("header %v has to have the table's primary key attributes %v", header, keyAttributes)
+		}
+	}
+	return columns, nil
+}
+
+// updater updates the items of the rows, it is safe to share it between the updates running concurrently.
+type updater struct {
+	svc           dynamodbiface.DynamoDBAPI
+	up            *UpdateParams
+	columns       []updateColumn
+	keyColumns    []int
+	definitions   map[string]string
+	hashAttribute string
+	limiter       *rateLimiter
+}
+
+// updateRows updates the items of the rows running at most Concurrency updates in parallel, counts the rows results,
+// and writes them into the report in the order of the rows. The update failing for the reason the rest of the rows
+// would fail for as well (i.e. the throttling after the SDK retries, or the expired credentials) stops the update once
+// the results of the rows are reported.
+func (u *updater) updateRows(rows []*updateRow, result *UpdateResult) error {
+	// the other failed update is the row's result rather than the error, so the rest of the rows are still updated
+	err := runConcurrently(len(rows), u.up.Concurrency, func(i int) error {
+		return u.updateRow(rows[i])
+	})
+	if reportErr := u.reportRows(rows, result); reportErr != nil {
+		return reportErr
+	}
+	return err
+}
+
+// reportRows counts the rows results, and writes them into the report in the order of the rows.
+func (u *updater) reportRows(rows []*updateRow, result *UpdateResult) error {
+	for _, row := range rows {
+		switch row.result {
+		case UpdateResultUpdated:
+			result.Updated++
+		case UpdateResultConditionFailed:
+			result.ConditionFailed++
+		case UpdateResultSkipped:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+		if u.up.Report == nil {
+			continue
+		}
+		report := []string{strconv.Itoa(row.line)}
+		for _, i := range u.keyColumns {
+			value := ""
+			if i < len(row.record) {
+				value = row.record[i]
+			}
+			report = append(report, value)
+		}
+		if err := u.up.Report.Write(append(report, row.result, row.message)); err != nil {
+			return err
+		}
+	}
+	if u.up.Report == nil {
+		return nil
+	}
+	return u.up.Report.Flush()
+}
+
+// updateRow updates the item of the row, and returns the error only if the update has to be stopped.
+func (u *updater) updateRow(row *updateRow) error {
+	input, err := u.updateItemInput(row.record)
+	if err != nil {
+		row.result, row.message = UpdateResultError, err.Error()
+		return nil
+	}
+	if input == nil {
+		row.result, row.message = UpdateResultSkipped, "nothing to update"
+		return nil
+	}
+	u.limiter.wait(1)
+	_, err = u.svc.UpdateItem(input)
+	if err != nil {
+		row.result, row.message = UpdateResultError, err.Error()
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			row.result, row.message = UpdateResultConditionFailed, aerr.Message()
+		}
+		if isUpdateStopped(err) {
+			return fmt.Errorf("failed to update line %d: %v", row.line, err)
+		}
+		return nil
+	}
+	row.result = UpdateResultUpdated
+	return nil
+}
+
+// isUpdateStopped returns whether the error is not specific to the item, so the rest of the items would fail as well.
+func isUpdateStopped(err error) bool {
+	if request.IsErrorThrottle(err) || request.IsErrorExpiredCreds(err) {
+		return true
+	}
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	case dynamodb.ErrCodeResourceNotFoundException, "NoCredentialProviders", "UnrecognizedClientException",
+		"InvalidSignatureException":
+		return true
+	}
+	return false
+}
+
+// updateItemInput builds the update of the item of the record, or returns nil if there is nothing to update.
+func (u *updater) updateItemInput(record []string) (*dynamodb.UpdateItemInput, error) {
+	if len(record) != len(u.columns) {
+		return nil, fmt.Errorf("row has %d values, but the header has %d columns", len(record), len(u.columns))
+	}
+	key := make(map[string]*dynamodb.AttributeValue)
+	var update expression.UpdateBuilder
+	var conditions []expression.ConditionBuilder
+	if len(u.up.Condition) != 0 {
+		conditions = append(conditions, u.up.Condition.conditionBuilder(u.definitions))
+	}
+	if !u.up.Upsert {
+		conditions = append(conditions, expression.AttributeExists(expression.Name(u.hashAttribute)))
+	}
+	updated := false
+	for i, column := range u.columns {
+		value := record[i]
+		name := expression.Name(column.attribute)
+		if column.condition && value == "" {
+			conditions = append(conditions, expression.AttributeNotExists(name))
+			continue
+		}
+		if value == "" && !column.key {
+			if u.up.RemoveEmpty {
+				update = update.Remove(name)
+				updated = true
+			}
+			continue
+		}
+		attributeType, ok := u.up.Types[column.attribute]
+		if !ok {
+			attributeType = u.definitions[column.attribute]
+		}
+		av, err := toAttributeValue(value, attributeType)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %v", column.attribute, err)
+		}
+		switch {
+		case column.key:
+			if av == nil {
+				return nil, fmt.Errorf("key attribute %s is empty", column.attribute)
+			}
+			key[column.attribute] = av
+		case column.condition:
+			if av == nil {
+				conditions = append(conditions, expression.AttributeNotExists(name))
+			} else {
+				conditions = append(conditions, expression.Equal(name, expression.Value(attributeValue{av: av})))
+			}
+		case av == nil:
+			// the empty set is removed, as DynamoDB doesn't allow the empty sets
+			update = update.Remove(name)
+			updated = true
+		default:
+			update = update.Set(name, expression.Value(attributeValue{av: av}))
+			updated = true
+		}
+	}
+	if !updated {
+		return nil, nil
+	}
+	builder := expression.NewBuilder().WithUpdate(update)
+	if len(conditions) != 0 {
+		condition := conditions[0]
+		for _, c := range conditions[1:] {
+			condition = condition.And(c)
+		}
+		builder = builder.WithCondition(condition)
+	}
+	expr, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(u.up.Table),
+		Key:                       key,
+		UpdateExpression:          expr.Update(),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}, nil
+}
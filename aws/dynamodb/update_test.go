@@ -0,0 +1,170 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type updateDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	mu      sync.Mutex
+	updates []*dynamodb.UpdateItemInput
+}
+
+// UpdateItem fails the condition check of the order 3, and is throttled (after the SDK retries) on the order 7.
+func (m *updateDynamoDBClient) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	m.mu.Lock()
+	m.updates = append(m.updates, input)
+	m.mu.Unlock()
+	if aws.StringValue(input.Key["orderId"].N) == "3" {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+	}
+	if aws.StringValue(input.Key["orderId"].N) == "7" {
+		return nil, awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throughput exceeded", nil)
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func TestUpdateItems(t *testing.T) {
+	svc := &updateDynamoDBClient{}
+	report := &recordingWriter{}
+	patch := `orderId,customerId,status,total,if:version
+1,c1,SHIPPED,,2
+2,c1,,,
+3,c1,SHIPPED,10,
+4,c1,SHIPPED
+x,c1,SHIPPED,,
+`
+	result, err := updateItems(svc, ordersDescription, strings.NewReader(patch),
+		&UpdateParams{Table: "orders", Concurrency: 2, Report: report})
+	if err != nil {
+		t.Fatalf("updateItems() error = %v", err)
+	}
+	wantResult := &UpdateResult{Updated: 1, ConditionFailed: 1, Skipped: 1, Failed: 2}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("updateItems() = %+v, want %+v", result, wantResult)
+	}
+	if want := []string{"line", "customerId", "orderId", "result", "message"}; !reflect.DeepEqual(report.header, want) {
+		t.Errorf("report header = %v, want %v", report.header, want)
+	}
+	results := make([]string, 0, len(report.records))
+	for _, record := range report.records {
+		results = append(results, strings.Join(record[:4], ","))
+	}
+	want := []string{
+		"2,c1,1,updated",
+		"3,c1,2,skipped",
+		"4,c1,3,condition_failed",
+		"5,c1,4,error",
+		"6,c1,x,error",
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("report = %v, want %v", results, want)
+	}
+	if len(svc.updates) != 2 {
+		t.Fatalf("updates = %d, want 2", len(svc.updates))
+	}
+}
+
+func TestUpdateItemsStopped(t *testing.T) {
+	svc := &updateDynamoDBClient{}
+	report := &recordingWriter{}
+	patch := "orderId,customerId,status\n"
+	for i := 5; i < 5+updateChunkSize+10; i++ {
+		patch += fmt.Sprintf("%d,c1,SHIPPED\n", i)
+	}
+	result, err := updateItems(svc, ordersDescription, strings.NewReader(patch),
+		&UpdateParams{Table: "orders", Concurrency: 1, Report: report})
+	if err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Fatalf("updateItems() error = %v, want throttling of line 4", err)
+	}
+	// the rest of the chunk is still run and reported, but the next chunk is not
+	wantResult := &UpdateResult{Updated: updateChunkSize - 1, Failed: 1}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("updateItems() = %+v, want %+v", result, wantResult)
+	}
+	if len(report.records) != updateChunkSize || report.records[2][3] != UpdateResultError {
+		t.Errorf("report = %d rows, line 4 = %v, want %d rows and error", len(report.records), report.records[2],
+			updateChunkSize)
+	}
+}
+
+func TestIsUpdateStopped(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throttled", nil), want: true},
+		{err: awserr.New("ThrottlingException", "throttled", nil), want: true},
+		{err: awserr.New("ExpiredTokenException", "expired", nil), want: true},
+		{err: awserr.New("NoCredentialProviders", "no credentials", nil), want: true},
+		{err: awserr.New(dynamodb.ErrCodeResourceNotFoundException, "no table", nil), want: true},
+		{err: awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil), want: false},
+		{err: awserr.New("ValidationException", "invalid", nil), want: false},
+	}
+	for _, tt := range tests {
+		if got := isUpdateStopped(tt.err); got != tt.want {
+			t.Errorf("isUpdateStopped(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateItemInput(t *testing.T) {
+	u := &updater{
+		up:            &UpdateParams{Table: "orders", Types: map[string]string{"tags": "SS"}, RemoveEmpty: true},
+		columns:       []updateColumn{{attribute: "customerId", key: true}, {attribute: "tags"}, {attribute: "note"}},
+		definitions:   definitionsMapping(ordersDescription.AttributeDefinitions),
+		hashAttribute: "customerId",
+	}
+	input, err := u.updateItemInput([]string{"c1", "[a,b,a]", ""})
+	if err != nil {
+		t.Fatalf("updateItemInput() error = %v", err)
+	}
+	if got, want := aws.StringValue(input.UpdateExpression), "REMOVE #1\nSET #2 = :0\n"; got != want {
+		t.Errorf("UpdateExpression = %q, want %q", got, want)
+	}
+	if got, want := aws.StringValue(input.ConditionExpression), "attribute_exists (#0)"; got != want {
+		t.Errorf("ConditionExpression = %q, want %q", got, want)
+	}
+	names := map[string]*string{"#0": aws.String("customerId"), "#1": aws.String("note"), "#2": aws.String("tags")}
+	if !reflect.DeepEqual(input.ExpressionAttributeNames, names) {
+		t.Errorf("ExpressionAttributeNames = %v, want %v", input.ExpressionAttributeNames, names)
+	}
+	values := map[string]*dynamodb.AttributeValue{":0": {SS: aws.StringSlice([]string{"a", "b"})}}
+	if !reflect.DeepEqual(input.ExpressionAttributeValues, values) {
+		t.Errorf("ExpressionAttributeValues = %v, want %v", input.ExpressionAttributeValues, values)
+	}
+	key := map[string]*dynamodb.AttributeValue{"customerId": {S: aws.String("c1")}}
+	if !reflect.DeepEqual(input.Key, key) {
+		t.Errorf("Key = %v, want %v", input.Key, key)
+	}
+}
+
+func TestUpdateColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		wantErr bool
+	}{
+		{name: "keys", header: []string{"customerId", "orderId", "status", "if:status"}},
+		{name: "missing key", header: []string{"customerId", "status"}, wantErr: true},
+		{name: "condition on key only", header: []string{"customerId", "if:orderId"}, wantErr: true},
+		{name: "duplicate", header: []string{"customerId", "orderId", "status", "status"}, wantErr: true},
+		{name: "empty condition", header: []string{"customerId", "orderId", "if:"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := updateColumns(tt.header, []string{"customerId", "orderId"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("updateColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
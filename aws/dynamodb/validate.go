@@ -0,0 +1,346 @@
+package dynamodb
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"github.com/zshamrock/dynocsv/output"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxItemSize      = 400 * 1024
+	maxHashKeySize   = 2048
+	maxRangeKeySize  = 1024
+	headerLine       = 1
+	tableKeySchemaID = "table"
+)
+
+// ValidationResult is the number of the rows validated, and of the rows and the issues found.
+type ValidationResult struct {
+	Rows        int64
+	InvalidRows int64
+	Issues      int64
+}
+
+// keyUsage is the use of the attribute as the key of the table or of the index.
+type keyUsage struct {
+	index   string
+	keyType string
+}
+
+// validator validates the rows of the CSV against the table's key schema, attribute definitions and indexes.
+type validator struct {
+	header        []string
+	types         map[string]string
+	definitions   map[string]string
+	keyAttributes []string
+	keys          map[string][]keyUsage
+	seen          map[string]int
+	report        output.Writer
+	result        *ValidationResult
+}
+
+// ValidateCSV validates the CSV, where the header names the attributes, before it is imported into the table, and
+// writes each issue found with its line number into the report. The values are converted into the attribute types the
+// same as by the import, so the rows, which have the key attributes missing or of the wrong types, the duplicate
+// primary keys, the values which don't parse as their types, the index key attributes of the wrong types, or which
+// are larger than 400 KB, are reported.
+func ValidateCSV(
+	sp *awssessions.SessionParams,
+	table string,
+	r io.Reader,
+	types map[string]string,
+	report output.Writer) (*ValidationResult, error) {
+
+	svc := dynamodb.New(awssessions.GetSession(sp))
+	return validateItems(describe(svc, table), r, types, report)
+}
+
+func validateItems(
+	desc *dynamodb.TableDescription,
+	r io.Reader,
+	types map[string]string,
+	report output.Writer) (*ValidationResult, error) {
+
+	reader := csv.NewReader(r)
+	// the rows longer than the header are reported as the issues
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("header is missing")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := report.WriteHeader([]string{"line", "attribute", "issue"}); err != nil {
+		return nil, err
+	}
+	v := &validator{
+		header:        header,
+		types:         types,
+		definitions:   definitionsMapping(desc.AttributeDefinitions),
+		keyAttributes: keyAttributeNames(desc),
+		keys:          keyUsages(desc),
+		seen:          make(map[string]int),
+		report:        report,
+		result:        &ValidationResult{},
+	}
+	if err := v.validateHeader(); err != nil {
+		return v.result, err
+	}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return v.result, err
+		}
+		if err := v.validateRow(line, record); err != nil {
+			return v.result, err
+		}
+	}
+	return v.result, report.Flush()
+}
+
+// keyUsages returns the uses of the attributes as the keys of the table and of its indexes.
+func keyUsages(desc *dynamodb.TableDescription) map[string][]keyUsage {
+	usages := make(map[string][]keyUsage)
+	add := func(index string, keySchema []*dynamodb.KeySchemaElement) {
+		for _, key := range keySchema {
+			attribute := aws.StringValue(key.AttributeName)
+			usages[attribute] = append(usages[attribute], keyUsage{index: index, keyType: aws.StringValue(key.KeyType)})
+		}
+	}
+	add(tableKeySchemaID, desc.KeySchema)
+	for _, index := range desc.GlobalSecondaryIndexes {
+		add(aws.StringValue(index.IndexName), index.KeySchema)
+	}
+	for _, index := range desc.LocalSecondaryIndexes {
+		add(aws.StringValue(index.IndexName), index.KeySchema)
+	}
+	return usages
+}
+
+// validateHeader checks the header has the table's primary key attributes, has no duplicate columns, and the types
+// set don't conflict with the key attributes types of the table and of its indexes.
+func (v *validator) validateHeader() error {
+	columns := make(map[string]bool, len(v.header))
+	for _, attribute := range v.header {
+		if attribute == "" {
+			continue
+		}
+		if columns[attribute] {
+			if err := v.issue(headerLine, attribute, "duplicate column"); err != nil {
+				return err
+			}
+		}
+		columns[attribute] = true
+	}
+	for _, attribute := range v.keyAttributes {
+		if !columns[attribute] {
+			if err := v.issue(headerLine, attribute, "primary key column is missing"); err != nil {
+				return err
+			}
+		}
+	}
+	for attribute, attributeType := range v.types {
+		definition, ok := v.definitions[attribute]
+		if !ok || attributeType == definition {
+			continue
+		}
+		for _, usage := range v.keys[attribute] {
+			issue := fmt.Sprintf("type %s conflicts with the %s key type %s", attributeType, usage.index, definition)
+			if err := v.issue(headerLine, attribute, issue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateRow converts the row into the item the same as the import, and reports the issues found.
+func (v *validator) validateRow(line int, record []string) error {
+	v.result.Rows++
+	issues := v.result.Issues
+	defer func() {
+		if v.result.Issues != issues {
+			v.result.InvalidRows++
+		}
+	}()
+	if len(record) > len(v.header) {
+		issue := fmt.Sprintf("row has %d values, but the header has only %d columns", len(record), len(v.header))
+		return v.issue(line, "", issue)
+	}
+	item := make(map[string]*dynamodb.AttributeValue, len(record))
+	invalid := make(map[string]bool)
+	for i, value := range record {
+		attribute := v.header[i]
+		if attribute == "" || value == "" {
+			continue
+		}
+		attributeType, ok := v.types[attribute]
+		if !ok {
+			attributeType = v.definitions[attribute]
+		}
+		av, err := toAttributeValue(value, attributeType)
+		if err != nil {
+			invalid[attribute] = true
+			if err := v.issue(line, attribute, v.keyIssue(attribute, err.Error())); err != nil {
+				return err
+			}
+			continue
+		}
+		if av == nil {
+			continue
+		}
+		item[attribute] = av
+		for _, usage := range v.keys[attribute] {
+			if err := v.validateKey(line, attribute, av, usage); err != nil {
+				return err
+			}
+		}
+	}
+	missing := false
+	for _, attribute := range v.keyAttributes {
+		if _, ok := item[attribute]; !ok {
+			missing = true
+			if invalid[attribute] {
+				continue
+			}
+			if err := v.issue(line, attribute, "primary key attribute is missing"); err != nil {
+				return err
+			}
+		}
+	}
+	if !missing {
+		id := keyID(item, v.keyAttributes)
+		if first, ok := v.seen[id]; ok {
+			if err := v.issue(line, "", fmt.Sprintf("duplicate primary key of line %d", first)); err != nil {
+				return err
+			}
+		} else {
+			v.seen[id] = line
+		}
+	}
+	if size := itemSize(item); size > maxItemSize {
+		issue := fmt.Sprintf("item size %d bytes is over the %d bytes limit", size, maxItemSize)
+		return v.issue(line, "", issue)
+	}
+	return nil
+}
+
+// validateKey checks the value of the key attribute of the table or of the index has the attribute's defined type and
+// is within the key size limit.
+func (v *validator) validateKey(line int, attribute string, av *dynamodb.AttributeValue, usage keyUsage) error {
+	definition := v.definitions[attribute]
+	var size int
+	switch {
+	case definition == dynamodb.ScalarAttributeTypeS && av.S != nil:
+		size = len(aws.StringValue(av.S))
+	case definition == dynamodb.ScalarAttributeTypeN && av.N != nil:
+		size = numberSize(aws.StringValue(av.N))
+	case definition == dynamodb.ScalarAttributeTypeB && av.B != nil:
+		size = len(av.B)
+	default:
+		return v.issue(line, attribute, v.keyIssue(attribute, fmt.Sprintf("value is not of type %s", definition)))
+	}
+	limit := maxHashKeySize
+	if usage.keyType == dynamodb.KeyTypeRange {
+		limit = maxRangeKeySize
+	}
+	if size > limit {
+		issue := fmt.Sprintf("%s %s key size %d bytes is over the %d bytes limit",
+			usage.index, strings.ToLower(usage.keyType), size, limit)
+		return v.issue(line, attribute, issue)
+	}
+	return nil
+}
+
+// keyIssue prefixes the issue with the keys the attribute is used as, if any.
+func (v *validator) keyIssue(attribute string, issue string) string {
+	usages := v.keys[attribute]
+	if len(usages) == 0 {
+		return issue
+	}
+	indexes := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		indexes = append(indexes, usage.index)
+	}
+	return fmt.Sprintf("%s key: %s", strings.Join(indexes, ", "), issue)
+}
+
+func (v *validator) issue(line int, attribute string, issue string) error {
+	v.result.Issues++
+	return v.report.Write([]string{strconv.Itoa(line), attribute, issue})
+}
+
+// itemSize estimates the size of the item the way DynamoDB calculates it: the attribute names lengths plus the values
+// sizes.
+func itemSize(item map[string]*dynamodb.AttributeValue) int {
+	size := 0
+	for attribute, av := range item {
+		size += len(attribute) + attributeValueSize(av)
+	}
+	return size
+}
+
+func attributeValueSize(av *dynamodb.AttributeValue) int {
+	switch {
+	case av.S != nil:
+		return len(aws.StringValue(av.S))
+	case av.N != nil:
+		return numberSize(aws.StringValue(av.N))
+	case av.B != nil:
+		return len(av.B)
+	case av.SS != nil:
+		size := 0
+		for _, s := range av.SS {
+			size += len(aws.StringValue(s))
+		}
+		return size
+	case av.NS != nil:
+		size := 0
+		for _, n := range av.NS {
+			size += numberSize(aws.StringValue(n))
+		}
+		return size
+	case av.BS != nil:
+		size := 0
+		for _, b := range av.BS {
+			size += len(b)
+		}
+		return size
+	case av.L != nil:
+		// the list has 3 bytes overhead plus 1 byte per element
+		size := 3
+		for _, e := range av.L {
+			size += 1 + attributeValueSize(e)
+		}
+		return size
+	case av.M != nil:
+		// the map has 3 bytes overhead plus 1 byte per element
+		size := 3
+		for k, e := range av.M {
+			size += 1 + len(k) + attributeValueSize(e)
+		}
+		return size
+	}
+	// BOOL and NULL
+	return 1
+}
+
+// numberSize is 1 byte per 2 significant digits plus 1 byte.
+func numberSize(n string) int {
+	n = strings.TrimLeft(n, "+-")
+	if i := strings.IndexAny(n, "eE"); i != -1 {
+		n = n[:i]
+	}
+	digits := strings.Trim(strings.Replace(n, ".", "", 1), "0")
+	return (len(digits)+1)/2 + 1
+}
@@ -0,0 +1,93 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateItems(t *testing.T) {
+	report := &recordingWriter{}
+	large := strings.Repeat("x", maxItemSize)
+	data := `customerId,orderId,status,total
+c1,1,NEW,10
+c1,1.0,NEW,20
+c1,x,NEW,
+,2,NEW,
+c1,3,NEW,1,extra
+c1,4,` + large + `,
+`
+	result, err := validateItems(ordersDescription, strings.NewReader(data), map[string]string{"total": "BOOL"}, report)
+	if err != nil {
+		t.Fatalf("validateItems() error = %v", err)
+	}
+	if want := (&ValidationResult{Rows: 6, InvalidRows: 6, Issues: 9}); !reflect.DeepEqual(result, want) {
+		t.Errorf("validateItems() = %+v, want %+v", result, want)
+	}
+	want := [][]string{
+		{"2", "total", `strconv.ParseBool: parsing "10": invalid syntax`},
+		{"3", "total", `strconv.ParseBool: parsing "20": invalid syntax`},
+		{"3", "", "duplicate primary key of line 2"},
+		{"4", "orderId", `table key: "x" is not a number`},
+		{"5", "customerId", "primary key attribute is missing"},
+		{"6", "", "row has 5 values, but the header has only 4 columns"},
+		{"7", "status", "byStatus hash key size 409600 bytes is over the 2048 bytes limit"},
+		{"7", "status", "byStatusLocal range key size 409600 bytes is over the 1024 bytes limit"},
+	}
+	if len(report.records) != len(want)+1 {
+		t.Fatalf("report = %v, want %v", report.records, want)
+	}
+	if !reflect.DeepEqual(report.records[:len(want)], want) {
+		t.Errorf("report = %v, want %v", report.records[:len(want)], want)
+	}
+	if got := report.records[len(want)]; got[0] != "7" || !strings.HasPrefix(got[2], "item size") {
+		t.Errorf("report = %v, want item size issue of line 7", got)
+	}
+}
+
+func TestValidateHeader(t *testing.T) {
+	report := &recordingWriter{}
+	data := "customerId,status,status\n"
+	result, err := validateItems(ordersDescription, strings.NewReader(data), map[string]string{"status": "N"}, report)
+	if err != nil {
+		t.Fatalf("validateItems() error = %v", err)
+	}
+	want := [][]string{
+		{"1", "status", "duplicate column"},
+		{"1", "orderId", "primary key column is missing"},
+		{"1", "status", "type N conflicts with the byStatus key type S"},
+		{"1", "status", "type N conflicts with the byStatusLocal key type S"},
+	}
+	if !reflect.DeepEqual(report.records, want) {
+		t.Errorf("report = %v, want %v", report.records, want)
+	}
+	if result.Issues != 4 || result.Rows != 0 {
+		t.Errorf("validateItems() = %+v, want 4 issues and no rows", result)
+	}
+}
+
+func TestItemSize(t *testing.T) {
+	tests := []struct {
+		name string
+		item map[string]*dynamodb.AttributeValue
+		want int
+	}{
+		{name: "string", item: map[string]*dynamodb.AttributeValue{"id": {S: aws.String("abc")}}, want: 5},
+		{name: "number", item: map[string]*dynamodb.AttributeValue{"n": {N: aws.String("-12.340")}}, want: 4},
+		{name: "bool", item: map[string]*dynamodb.AttributeValue{"b": {BOOL: aws.Bool(true)}}, want: 2},
+		{
+			name: "list",
+			item: map[string]*dynamodb.AttributeValue{"l": {L: []*dynamodb.AttributeValue{{S: aws.String("ab")}}}},
+			want: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemSize(tt.item); got != tt.want {
+				t.Errorf("itemSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package dynamodb
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/zshamrock/dynocsv/output"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MissingPartitionValue is used as the partition value for the items which don't have the partition attribute set.
+	MissingPartitionValue = "__missing__"
+	// UnmatchedEntityValue is used as the entity for the items which attribute value doesn't match the entity pattern.
+	UnmatchedEntityValue = "__unmatched__"
+
+	partitionFileFormat = "%s=%s.csv"
+	entityFileFormat    = "%s.csv"
+)
+
+// Writers routes each item into the output identified by the key, all items routed under the same key share the same
+// CSV headers, which are detected only from these items.
+type Writers interface {
+	// Route returns the key of the output the item should be written into, it can also extend the item with the
+	// attributes derived from the existing ones.
+	Route(item map[string]*dynamodb.AttributeValue) string
+	// Writer returns the writer of the output, it is called only once per key.
+	Writer(key string) output.Writer
+	// Initial returns the keys of the outputs which have to be written even if no items are routed into them.
+	Initial() []string
+	// KeysOnly returns whether the outputs start with the primary key attributes only rather than with the baseline
+	// attributes of the whole table, so all the rest of the attributes are detected from their own items.
+	KeysOnly() bool
+}
+
+type singleWriter struct {
+	writer output.Writer
+}
+
+// SingleWriter writes all the items into the single output.
+func SingleWriter(writer output.Writer) Writers {
+	return singleWriter{writer: writer}
+}
+
+func (w singleWriter) Route(map[string]*dynamodb.AttributeValue) string {
+	return ""
+}
+
+func (w singleWriter) Writer(string) output.Writer {
+	return w.writer
+}
+
+func (w singleWriter) Initial() []string {
+	return []string{""}
+}
+
+func (w singleWriter) KeysOnly() bool {
+	return false
+}
+
+type partitionWriters struct {
+	attribute string
+	dir       string
+	files     *output.Files
+}
+
+// PartitionWriters writes the items into the separate file per distinct value of the attribute, i.e.
+// <dir>/<attribute>=<value>.csv.
+func PartitionWriters(attribute string, dir string, files *output.Files) Writers {
+	return partitionWriters{attribute: attribute, dir: dir, files: files}
+}
+
+func (w partitionWriters) Route(item map[string]*dynamodb.AttributeValue) string {
+	av, ok := item[w.attribute]
+	if !ok {
+		return MissingPartitionValue
+	}
+	value, handled := getValue(av)
+	if !handled {
+		return MissingPartitionValue
+	}
+	return value
+}
+
+func (w partitionWriters) Writer(key string) output.Writer {
+	return w.files.Writer(filepath.Join(w.dir, partitionFilename(w.attribute, key)))
+}
+
+func (w partitionWriters) Initial() []string {
+	return []string{}
+}
+
+func (w partitionWriters) KeysOnly() bool {
+	return false
+}
+
+// partitionFilename escapes the value, so it is safe to be used as the file name, and distinct values still produce
+// distinct file names.
+func partitionFilename(attribute string, value string) string {
+	return fmt.Sprintf(partitionFileFormat, url.PathEscape(attribute), url.PathEscape(value))
+}
+
+// EntityParams represents how the entity type is resolved from the attribute value of the single-table design items,
+// and optionally split the attribute value (i.e. composite key "ORDER#2020#123") into the extra named columns.
+type EntityParams struct {
+	Attribute string
+	Pattern   *regexp.Regexp
+	// KeyColumns are the names of the columns the attribute value parts are set into, empty name skips the part
+	KeyColumns   []string
+	KeySeparator string
+}
+
+type entityWriters struct {
+	params EntityParams
+	dir    string
+	files  *output.Files
+}
+
+// EntityWriters writes the items into the separate file per entity type, i.e. <dir>/<entity>.csv, where the entity type
+// is the first capturing group (or the whole match if there is none) of the pattern matched against the attribute value.
+func EntityWriters(params EntityParams, dir string, files *output.Files) Writers {
+	return entityWriters{params: params, dir: dir, files: files}
+}
+
+func (w entityWriters) Route(item map[string]*dynamodb.AttributeValue) string {
+	av, ok := item[w.params.Attribute]
+	if !ok {
+		return MissingPartitionValue
+	}
+	value, handled := getValue(av)
+	if !handled {
+		return MissingPartitionValue
+	}
+	w.splitKey(item, value)
+	match := w.params.Pattern.FindStringSubmatch(value)
+	if match == nil {
+		return UnmatchedEntityValue
+	}
+	entity := match[0]
+	if len(match) > 1 {
+		entity = match[1]
+	}
+	if entity == "" {
+		return UnmatchedEntityValue
+	}
+	return entity
+}
+
+func (w entityWriters) splitKey(item map[string]*dynamodb.AttributeValue, value string) {
+	if len(w.params.KeyColumns) == 0 {
+		return
+	}
+	parts := strings.Split(value, w.params.KeySeparator)
+	for i, column := range w.params.KeyColumns {
+		if column == "" || i >= len(parts) {
+			continue
+		}
+		item[column] = &dynamodb.AttributeValue{S: aws.String(parts[i])}
+	}
+}
+
+func (w entityWriters) Writer(key string) output.Writer {
+	return w.files.Writer(filepath.Join(w.dir, fmt.Sprintf(entityFileFormat, url.PathEscape(key))))
+}
+
+func (w entityWriters) Initial() []string {
+	return []string{}
+}
+
+func (w entityWriters) KeysOnly() bool {
+	return true
+}
@@ -0,0 +1,233 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/zshamrock/dynocsv/output"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+type recordingWriter struct {
+	header  []string
+	records [][]string
+}
+
+func (w *recordingWriter) WriteHeader(attributes []string) error {
+	if w.header == nil {
+		w.header = append([]string(nil), attributes...)
+	}
+	return nil
+}
+
+func (w *recordingWriter) Write(record []string) error {
+	w.records = append(w.records, record)
+	return nil
+}
+
+func (w *recordingWriter) Flush() error {
+	return nil
+}
+
+type recordingWriters struct {
+	attribute string
+	writers   map[string]*recordingWriter
+}
+
+func (w recordingWriters) Route(item map[string]*dynamodb.AttributeValue) string {
+	return partitionWriters{attribute: w.attribute}.Route(item)
+}
+
+func (w recordingWriters) Writer(key string) output.Writer {
+	writer := &recordingWriter{}
+	w.writers[key] = writer
+	return writer
+}
+
+func (w recordingWriters) Initial() []string {
+	return []string{}
+}
+
+func (w recordingWriters) KeysOnly() bool {
+	return false
+}
+
+func TestPartitionWritersRoute(t *testing.T) {
+	tests := []struct {
+		name string
+		item map[string]*dynamodb.AttributeValue
+		want string
+	}{
+		{
+			name: "string value",
+			item: map[string]*dynamodb.AttributeValue{"tenant": {S: aws.String("acme")}},
+			want: "acme",
+		},
+		{
+			name: "number value",
+			item: map[string]*dynamodb.AttributeValue{"tenant": {N: aws.String("10")}},
+			want: "10",
+		},
+		{
+			name: "missing value",
+			item: map[string]*dynamodb.AttributeValue{"name": {S: aws.String("Hippo")}},
+			want: MissingPartitionValue,
+		},
+		{
+			name: "unsupported value type",
+			item: map[string]*dynamodb.AttributeValue{"tenant": {B: []byte("acme")}},
+			want: MissingPartitionValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PartitionWriters("tenant", "", nil).Route(tt.item); got != tt.want {
+				t.Errorf("Route() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartitionFilename(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "plain value", value: "acme", want: "tenant=acme.csv"},
+		{name: "value with path separator", value: "a/b", want: "tenant=a%2Fb.csv"},
+		{name: "value with space", value: "a b", want: "tenant=a%20b.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := partitionFilename("tenant", tt.value); got != tt.want {
+				t.Errorf("partitionFilename() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExporterProcessPartitionHeaders(t *testing.T) {
+	writers := recordingWriters{attribute: "tenant", writers: make(map[string]*recordingWriter)}
+	e := &exporter{
+		skipAttributes: map[string]bool{},
+		attributes:     []string{"Id", "tenant"},
+		attributesSet:  map[string]bool{"Id": true, "tenant": true},
+		writers:        writers,
+		sinks:          make(map[string]*sink),
+	}
+	e.process([]map[string]*dynamodb.AttributeValue{
+		{"Id": {S: aws.String("1")}, "tenant": {S: aws.String("acme")}, "A": {S: aws.String("a")}},
+		{"Id": {S: aws.String("2")}, "tenant": {S: aws.String("zoo")}, "B": {S: aws.String("b")}},
+		{"Id": {S: aws.String("3")}, "tenant": {S: aws.String("acme")}},
+	}, true)
+	want := map[string]*recordingWriter{
+		"acme": {header: []string{"Id", "tenant", "A"}, records: [][]string{{"1", "acme", "a"}, {"3", "acme", ""}}},
+		"zoo":  {header: []string{"Id", "tenant", "B"}, records: [][]string{{"2", "zoo", "b"}}},
+	}
+	if !reflect.DeepEqual(writers.writers, want) {
+		for key, writer := range writers.writers {
+			t.Errorf("process() %s = %v, want %v", key, writer, want[key])
+		}
+	}
+}
+
+func TestEntityWritersRoute(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		keyColumns []string
+		item       map[string]*dynamodb.AttributeValue
+		want       string
+		wantItem   map[string]*dynamodb.AttributeValue
+	}{
+		{
+			name:    "entity from capturing group",
+			pattern: "^([A-Z]+)#",
+			item:    map[string]*dynamodb.AttributeValue{"SK": {S: aws.String("ORDER#2020#123")}},
+			want:    "ORDER",
+		},
+		{
+			name:    "entity from whole match",
+			pattern: "^[A-Z]+",
+			item:    map[string]*dynamodb.AttributeValue{"SK": {S: aws.String("USER#1")}},
+			want:    "USER",
+		},
+		{
+			name:    "unmatched entity",
+			pattern: "^([A-Z]+)#",
+			item:    map[string]*dynamodb.AttributeValue{"SK": {S: aws.String("order")}},
+			want:    UnmatchedEntityValue,
+		},
+		{
+			name:    "missing entity attribute",
+			pattern: "^([A-Z]+)#",
+			item:    map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("USER#1")}},
+			want:    MissingPartitionValue,
+		},
+		{
+			name:       "split composite key into columns",
+			pattern:    "^([A-Z]+)#",
+			keyColumns: []string{"", "year", "id", "extra"},
+			item:       map[string]*dynamodb.AttributeValue{"SK": {S: aws.String("ORDER#2020#123")}},
+			want:       "ORDER",
+			wantItem: map[string]*dynamodb.AttributeValue{
+				"SK":   {S: aws.String("ORDER#2020#123")},
+				"year": {S: aws.String("2020")},
+				"id":   {S: aws.String("123")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := EntityWriters(EntityParams{
+				Attribute:    "SK",
+				Pattern:      regexp.MustCompile(tt.pattern),
+				KeyColumns:   tt.keyColumns,
+				KeySeparator: "#",
+			}, "", nil)
+			if got := w.Route(tt.item); got != tt.want {
+				t.Errorf("Route() = %v, want %v", got, tt.want)
+			}
+			if tt.wantItem != nil && !reflect.DeepEqual(tt.item, tt.wantItem) {
+				t.Errorf("Route() item = %v, want %v", tt.item, tt.wantItem)
+			}
+		})
+	}
+}
+
+func TestDefineKeyAttributes(t *testing.T) {
+	table := &dynamodb.TableDescription{
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("PK"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String("SK"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			{
+				IndexName: aws.String("GSI1"),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String("GSI1PK"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+					{AttributeName: aws.String("SK"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name           string
+		index          string
+		skipAttributes map[string]bool
+		want           []string
+	}{
+		{name: "table keys", want: []string{"PK", "SK"}},
+		{name: "index keys first", index: "GSI1", want: []string{"GSI1PK", "SK", "PK"}},
+		{name: "skip table key", skipAttributes: map[string]bool{"SK": true}, want: []string{"PK"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defineKeyAttributes(table, tt.index, tt.skipAttributes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("defineKeyAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Filename is the name of the config file looked up in the working dir, and then in the $HOME dir.
+	Filename = ".dynocsv.yaml"
+
+	homeEnvName = "HOME"
+)
+
+// Preset represents the named set of the flag values (by the flag names, i.e. "table", "columns", "sort-ge", etc.).
+// The repeatable flags (i.e. "filter") have the list of the values, the single value is the list of one value.
+type Preset map[string]Values
+
+// Values represents the value of the preset setting, either the single value, or the list of the values.
+type Values []string
+
+// UnmarshalYAML unmarshals either the single value or the list of the values.
+func (v *Values) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err == nil {
+		*v = Values{value}
+		return nil
+	}
+	var values []string
+	if err := unmarshal(&values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// Config represents the defaults of the AWS connection and the named presets.
+type Config struct {
+	Profile     string            `yaml:"profile"`
+	Region      string            `yaml:"region"`
+	EndpointURL string            `yaml:"endpoint-url"`
+	Presets     map[string]Preset `yaml:"presets"`
+}
+
+// Load loads the config from the working dir, or if it is not there from the $HOME dir. If neither exists, the empty
+// config is returned. All values have the env vars interpolated, i.e. "orders-${DATE}.csv".
+func Load() (*Config, error) {
+	dirs := []string{"."}
+	if home, ok := os.LookupEnv(homeEnvName); ok {
+		dirs = append(dirs, home)
+	}
+	for _, dir := range dirs {
+		filename := filepath.Join(dir, Filename)
+		data, err := ioutil.ReadFile(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		config, err := parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+		}
+		return config, nil
+	}
+	return &Config{}, nil
+}
+
+func parse(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	config.Profile = os.ExpandEnv(config.Profile)
+	config.Region = os.ExpandEnv(config.Region)
+	config.EndpointURL = os.ExpandEnv(config.EndpointURL)
+	for _, preset := range config.Presets {
+		for _, values := range preset {
+			for i, value := range values {
+				values[i] = os.ExpandEnv(value)
+			}
+		}
+	}
+	return config, nil
+}
+
+// Preset returns the preset by its name.
+func (c *Config) Preset(name string) (Preset, error) {
+	preset, ok := c.Presets[name]
+	if !ok {
+		return nil, fmt.Errorf("preset %q is not defined in %s", name, Filename)
+	}
+	return preset, nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		envs    map[string]string
+		want    *Config
+		wantErr bool
+	}{
+		{
+			name: "defaults and presets",
+			data: `
+profile: prod
+region: eu-west-1
+endpoint-url: http://localhost:8000
+presets:
+  orders:
+    table: orders
+    columns: id,total
+    limit: 100
+`,
+			want: &Config{
+				Profile:     "prod",
+				Region:      "eu-west-1",
+				EndpointURL: "http://localhost:8000",
+				Presets: map[string]Preset{
+					"orders": {"table": {"orders"}, "columns": {"id,total"}, "limit": {"100"}},
+				},
+			},
+		},
+		{
+			name: "env vars interpolation",
+			data: `
+profile: ${DYNOCSV_TEST_PROFILE}
+presets:
+  orders:
+    sort-ge: $DYNOCSV_TEST_DATE
+    output: orders-${DYNOCSV_TEST_DATE}.csv
+`,
+			envs: map[string]string{"DYNOCSV_TEST_PROFILE": "prod", "DYNOCSV_TEST_DATE": "2020-05-01"},
+			want: &Config{
+				Profile: "prod",
+				Presets: map[string]Preset{
+					"orders": {"sort-ge": {"2020-05-01"}, "output": {"orders-2020-05-01.csv"}},
+				},
+			},
+		},
+		{
+			name: "repeated values",
+			data: `
+presets:
+  active-orders:
+    table: orders
+    filter:
+      - status=active
+      - total>${DYNOCSV_TEST_TOTAL}
+`,
+			envs: map[string]string{"DYNOCSV_TEST_TOTAL": "100"},
+			want: &Config{
+				Presets: map[string]Preset{
+					"active-orders": {"table": {"orders"}, "filter": {"status=active", "total>100"}},
+				},
+			},
+		},
+		{
+			name:    "invalid value",
+			data:    "presets:\n  orders:\n    table:\n      name: orders\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown setting",
+			data:    "format: json",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.envs {
+				_ = os.Setenv(k, v)
+			}
+			got, err := parse([]byte(tt.data))
+			for k := range tt.envs {
+				_ = os.Unsetenv(k)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigPreset(t *testing.T) {
+	config := &Config{Presets: map[string]Preset{"orders": {"table": {"orders"}}}}
+	if got, err := config.Preset("orders"); err != nil || !reflect.DeepEqual(got, Preset{"table": {"orders"}}) {
+		t.Errorf("Preset() = %v, %v, want %v", got, err, Preset{"table": {"orders"}})
+	}
+	if _, err := config.Preset("users"); err == nil {
+		t.Errorf("Preset() error = nil, want error for undefined preset")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const convertCommandName = "convert"
+
+func convertCommand() cli.Command {
+	return cli.Command{
+		Name:  convertCommandName,
+		Usage: "convert DynamoDB export to S3 files (DynamoDB JSON or Amazon Ion) into CSV locally",
+		UsageText: fmt.Sprintf(`%s %s
+        --input/-i                <directory of .json.gz/.ion.gz files, or file>
+        [--from-schema            <schema file to take key attributes from>]
+        [--columns/-c             <comma separated columns>]
+        [--skip-columns/-sc       <comma separated columns to skip>]
+        [--limit/-l               <number>]
+        [--output/-o              <output file name>]
+        [--split-rows             <number of rows per file>]
+        [--split-size             <size per file, i.e. 500MB>]
+        [--partition-by           <attribute to write separate file per value>]
+        [--max-open-files         <number>]
+        [--entity-by              <attribute to write separate file per entity type>]
+        [--entity-pattern         <regexp to extract entity type>]
+        [--entity-key-columns     <comma separated columns>]
+        [--entity-key-separator   <composite key separator>]
+        [--group-by               <comma separated attributes to group by>]
+        [--agg                    <comma separated aggregations>]
+        [--max-groups             <number>]`,
+			appName, convertCommandName),
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s, i", inputFlagName),
+				Usage: "directory of the export (i.e. the downloaded AWSDynamoDB/<export id>/data) with the " +
+					".json.gz or .ion.gz files, read with its subdirectories, or the single file",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", fromSchemaFlagName),
+				Usage: "schema file written by \"describe --json\" or \"export --with-schema\", the table's and " +
+					"indexes' key attributes of which come first, otherwise the columns start with the attributes " +
+					"of the first item",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s, c", columnsFlagName),
+				Usage: fmt.Sprintf("columns to write, if omitted, all columns will be written (mutually exclusive "+
+					"with \"%s\")", skipColumnsFlagName),
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s, sc", skipColumnsFlagName),
+				Usage: fmt.Sprintf("columns to skip, if omitted, all columns will be written (mutually exclusive "+
+					"with \"%s\")", columnsFlagName),
+			},
+			cli.UintFlag{
+				Name:  fmt.Sprintf("%s, l", limitFlagName),
+				Usage: "limit number of records written, if not set (i.e. 0) all items are converted",
+			},
+		}, outputFlags()...),
+		Action: convert,
+	}
+}
+
+func convert(c *cli.Context) error {
+	input := mustFlag(c, inputFlagName)
+	columns, skipColumns := c.String(columnsFlagName), c.String(skipColumnsFlagName)
+	if columns != "" && skipColumns != "" {
+		return fmt.Errorf("both \"%s\" and \"%s\" are provided, they are mutually exclusive, please, use one",
+			columnsFlagName, skipColumnsFlagName)
+	}
+	files, err := dynamodb.ExportFiles(input)
+	if err != nil {
+		return err
+	}
+	var schema *dynamodb.TableSchema
+	name := filepath.Base(filepath.Clean(input))
+	for _, ext := range []string{".gz", ".json", ".ion"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if path := c.String(fromSchemaFlagName); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if schema, err = dynamodb.ReadTableSchema(file); err != nil {
+			return err
+		}
+		name = schema.Name
+	}
+	writers, closer, err := openWriters(c, name)
+	if err != nil {
+		return err
+	}
+	headers, err := dynamodb.ConvertExport(files, schema, columns, skipColumns, c.Uint(limitFlagName), writers)
+	if closeErr := closer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	printHeaders(c, headers)
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	fromProfileFlagName   = "from-profile"
+	fromRegionFlagName    = "from-region"
+	toTableFlagName       = "to-table"
+	toProfileFlagName     = "to-profile"
+	toRegionFlagName      = "to-region"
+	toEndpointURLFlagName = "to-endpoint-url"
+	toRoleARNFlagName     = "to-role-arn"
+	toExternalIDFlagName  = "to-external-id"
+	toMFASerialFlagName   = "to-mfa-serial"
+	dropFlagName          = "drop"
+	renameFlagName        = "rename"
+	setFlagName           = "set"
+	stateFlagName         = "state"
+
+	copyCommandName = "copy"
+)
+
+func copyCommand() cli.Command {
+	return cli.Command{
+		Name:  copyCommandName,
+		Usage: "copy items from the table into another table, possibly in the different account or region",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t                                     <source table>
+        --to-table                                     <destination table>
+        [--from-profile                                <source AWS profile>]
+        [--from-region                                 <source AWS region>]
+        [--to-profile                                  <destination AWS profile>]
+        [--to-region                                   <destination AWS region>]
+        [--to-endpoint-url                             <destination endpoint URL>]
+        [--to-role-arn                                 <destination role to assume>]
+        [--to-external-id                              <external id of the destination role>]
+        [--to-mfa-serial                               <MFA device of the destination role>]
+        [--index/-i                                    <index to query or scan instead of table>]
+        [--hash                                        <hash value>]
+        [--sort                                        <sort value>]
+        [--sort-[gt, ge, lt, le, begins-with, between] <sort value>]
+        [--since                                       <duration, i.e. 24h, or time>]
+        [--until                                       <duration, i.e. 24h, or time>]
+        [--sort-format                                 <iso, epoch or epoch-ms>]
+        [--filter                                      <attribute><operator><value>]...
+        [--segments                                    <number of parallel scan segments>]
+        [--drop                                        <attribute>]...
+        [--rename                                      <attribute>=<new attribute>]...
+        [--set                                         <attribute>=<value>]...
+        [--rate                                        <max items written per second>]
+        [--state                                       <file to save the progress into and resume from>]
+        [--with-schema]`,
+			appName, copyCommandName),
+		Flags: append(append([]cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table to copy items from",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", toTableFlagName),
+				Usage: "table to copy items into",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", fromProfileFlagName),
+				Usage: fmt.Sprintf("AWS profile to read the source table with, otherwise \"%s\" is used", profileFlagName),
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", fromRegionFlagName),
+				Usage: fmt.Sprintf("AWS region of the source table, otherwise \"%s\" is used", regionFlagName),
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", toProfileFlagName),
+				Usage: "AWS profile to write the destination table with, otherwise the source profile is used",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", toRegionFlagName),
+				Usage: "AWS region of the destination table, otherwise the source region is used",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", toEndpointURLFlagName),
+				Usage: fmt.Sprintf("DynamoDB endpoint URL of the destination table, otherwise \"%s\" is used",
+					endpointURLFlagName),
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", toRoleARNFlagName),
+				Usage: fmt.Sprintf("role to assume to write the destination table, \"%s\", \"%s\" and \"%s\" are "+
+					"used only if none of \"%s\", \"%s\" and \"%s\" is set", roleARNFlagName, externalIDFlagName,
+					mfaSerialFlagName, toProfileFlagName, toEndpointURLFlagName, toRoleARNFlagName),
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", toExternalIDFlagName),
+				Usage: fmt.Sprintf("external id to pass while assuming the destination role (see \"%s\")",
+					toRoleARNFlagName),
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", toMFASerialFlagName),
+				Usage: fmt.Sprintf("serial number (or ARN) of the MFA device required to assume the destination role "+
+					"(see \"%s\"), the MFA token code is prompted on start", toRoleARNFlagName),
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, i", indexFlagName),
+				Usage: "index to query or scan instead of table",
+			},
+		}, queryFlags()...),
+			cli.UintFlag{
+				Name: fmt.Sprintf("%s", segmentsFlagName),
+				Usage: "number of segments the scan is split into and run in parallel (or the number of the hashes " +
+					"queried in parallel)",
+				Value: defaultSegments,
+			},
+			cli.StringSliceFlag{
+				Name:  fmt.Sprintf("%s", dropFlagName),
+				Usage: "attribute to drop from the copied items, could be repeated",
+			},
+			cli.StringSliceFlag{
+				Name:  fmt.Sprintf("%s", renameFlagName),
+				Usage: "attribute to rename in the copied items, i.e. \"customerId=clientId\", could be repeated",
+			},
+			cli.StringSliceFlag{
+				Name: fmt.Sprintf("%s", setFlagName),
+				Usage: "attribute to set in the copied items, i.e. \"env=dev\", the type is inferred the same as by " +
+					"the import, could be repeated",
+			},
+			cli.Float64Flag{
+				Name:  fmt.Sprintf("%s", rateFlagName),
+				Usage: "max number of items written per second, 0 means no limit",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", stateFlagName),
+				Usage: "file to save the progress into after each page, the copy is resumed from it if it already exists",
+			},
+			cli.BoolFlag{
+				Name: fmt.Sprintf("%s", withSchemaFlagName),
+				Usage: "create the destination table with the source table schema before copying, unless it " +
+					"already exists",
+			},
+		),
+		Action: copyTable,
+	}
+}
+
+func copyTable(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	qp, err := queryParams(c)
+	if err != nil {
+		return err
+	}
+	filter, err := dynamodb.ParseFilter(c.StringSlice(filterFlagName))
+	if err != nil {
+		return err
+	}
+	transform, err := dynamodb.ParseTransform(
+		c.StringSlice(dropFlagName), c.StringSlice(renameFlagName), c.StringSlice(setFlagName))
+	if err != nil {
+		return err
+	}
+	from := sessionParams(c, cfg)
+	if c.IsSet(fromProfileFlagName) {
+		from.Profile = c.String(fromProfileFlagName)
+	}
+	if c.IsSet(fromRegionFlagName) {
+		from.Region = c.String(fromRegionFlagName)
+	}
+	to := *from
+	// the source role is never assumed silently in the destination account, its credentials are set explicitly
+	if c.IsSet(toProfileFlagName) || c.IsSet(toEndpointURLFlagName) || c.IsSet(toRoleARNFlagName) {
+		to.RoleARN = c.String(toRoleARNFlagName)
+		to.ExternalID = c.String(toExternalIDFlagName)
+		to.MFASerial = c.String(toMFASerialFlagName)
+	} else if c.IsSet(toExternalIDFlagName) || c.IsSet(toMFASerialFlagName) {
+		return fmt.Errorf("\"%s\" and \"%s\" require \"%s\"", toExternalIDFlagName, toMFASerialFlagName,
+			toRoleARNFlagName)
+	}
+	if c.IsSet(toProfileFlagName) {
+		to.Profile = c.String(toProfileFlagName)
+	}
+	if c.IsSet(toRegionFlagName) {
+		to.Region = c.String(toRegionFlagName)
+	}
+	if c.IsSet(toEndpointURLFlagName) {
+		to.EndpointURL = c.String(toEndpointURLFlagName)
+	}
+	table, toTable := mustFlag(c, tableFlagName), mustFlag(c, toTableFlagName)
+	if c.Bool(withSchemaFlagName) {
+		schema, err := dynamodb.DescribeTable(from, table)
+		if err != nil {
+			return err
+		}
+		if err := dynamodb.CreateTable(&to, schema, toTable, true); err != nil {
+			return err
+		}
+	}
+	written, err := dynamodb.CopyTable(from, &to, &dynamodb.CopyParams{
+		Table:     table,
+		Index:     c.String(indexFlagName),
+		Query:     qp,
+		Filter:    filter,
+		Segments:  c.Uint(segmentsFlagName),
+		ToTable:   toTable,
+		Transform: transform,
+		Rate:      c.Float64(rateFlagName),
+		State:     c.String(stateFlagName),
+	})
+	fmt.Printf("Copied %d items\n", written)
+	return err
+}
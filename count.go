@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+)
+
+const (
+	segmentsFlagName = "segments"
+
+	defaultSegments = 4
+
+	countCommandName = "count"
+)
+
+func countCommand() cli.Command {
+	return cli.Command{
+		Name:  countCommandName,
+		Usage: "count items of the table, query or filter using Select COUNT, without fetching any item data",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t                                     <table>
+        [--index/-i                                    <index to query or scan instead of table>]
+        [--hash                                        <hash value>]
+        [--sort                                        <sort value>]
+        [--sort-[gt, ge, lt, le, begins-with, between] <sort value>]
+        [--since                                       <duration, i.e. 24h, or time>]
+        [--until                                       <duration, i.e. 24h, or time>]
+        [--sort-format                                 <iso, epoch or epoch-ms>]
+        [--filter                                      <attribute><operator><value>]...
+        [--segments                                    <number of parallel scan segments>]
+        [--json]`,
+			appName, countCommandName),
+		Flags: append(append([]cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table to count items of",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, i", indexFlagName),
+				Usage: "index to query or scan instead of table",
+			},
+		}, queryFlags()...),
+			cli.UintFlag{
+				Name:  fmt.Sprintf("%s", segmentsFlagName),
+				Usage: "number of segments the scan is split into and run in parallel (not used by the query)",
+				Value: defaultSegments,
+			},
+			cli.BoolFlag{
+				Name:  fmt.Sprintf("%s", jsonFlagName),
+				Usage: "output the count as JSON",
+			},
+		),
+		Action: count,
+	}
+}
+
+func count(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	qp, err := queryParams(c)
+	if err != nil {
+		return err
+	}
+	filter, err := dynamodb.ParseFilter(c.StringSlice(filterFlagName))
+	if err != nil {
+		return err
+	}
+	result, err := dynamodb.CountItems(sessionParams(c, cfg), mustFlag(c, tableFlagName), c.String(indexFlagName), qp,
+		filter, c.Uint(segmentsFlagName))
+	if err != nil {
+		return err
+	}
+	if c.Bool(jsonFlagName) {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	fmt.Printf("Count: %d\nScanned count: %d\n", result.Count, result.ScannedCount)
+	return nil
+}
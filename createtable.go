@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+)
+
+const (
+	fromSchemaFlagName = "from-schema"
+
+	createTableCommandName = "create-table"
+)
+
+func createTableCommand() cli.Command {
+	return cli.Command{
+		Name:  createTableCommandName,
+		Usage: "create the empty table from the schema written by \"describe --json\" or \"export --with-schema\"",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --from-schema <schema file or "-" for stdin>
+        [--table/-t   <table name, otherwise the name from the schema>]`,
+			appName, createTableCommandName),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", fromSchemaFlagName),
+				Usage: "schema file to create the table from, or \"-\" to read it from stdin",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table to create, otherwise the table name from the schema is used",
+			},
+		},
+		Action: createTable,
+	}
+}
+
+func createTable(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if path := mustFlag(c, fromSchemaFlagName); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	schema, err := dynamodb.ReadTableSchema(r)
+	if err != nil {
+		return err
+	}
+	table := c.String(tableFlagName)
+	if table == "" {
+		table = schema.Name
+	}
+	if err := dynamodb.CreateTable(sessionParams(c, cfg), schema, table, false); err != nil {
+		return err
+	}
+	fmt.Printf("Created table %s\n", table)
+	return nil
+}
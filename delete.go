@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"github.com/zshamrock/dynocsv/output"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"strings"
+)
+
+const (
+	dryRunFlagName = "dry-run"
+	yesFlagName    = "yes"
+	backupFlagName = "backup"
+
+	deleteCommandName = "delete"
+)
+
+func deleteCommand() cli.Command {
+	return cli.Command{
+		Name:  deleteCommandName,
+		Usage: "delete items of the table matching the query, filter or keys using BatchWriteItem",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t                                     <table>
+        [--index/-i                                    <index to query or scan instead of table>]
+        [--hash                                        <hash value>]
+        [--hash-file                                   <file with hash value per line, or - for stdin>]
+        [--keys-file                                   <CSV file with primary keys, or - for stdin>]
+        [--sort                                        <sort value>]
+        [--sort-[gt, ge, lt, le, begins-with, between] <sort value>]
+        [--since                                       <duration, i.e. 24h, or time>]
+        [--until                                       <duration, i.e. 24h, or time>]
+        [--sort-format                                 <iso, epoch or epoch-ms>]
+        [--filter                                      <attribute><operator><value>]...
+        [--segments                                    <number of parallel scan segments>]
+        [--rate                                        <max items deleted per second>]
+        [--backup                                      <CSV file to write items into before deleting>]
+        [--dry-run]
+        [--yes]`,
+			appName, deleteCommandName),
+		Flags: append(append([]cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table to delete items from",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, i", indexFlagName),
+				Usage: "index to query or scan instead of table to select the items to delete",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", keysFileFlagName),
+				Usage: fmt.Sprintf("delete the items by the primary keys read from the CSV file, or from stdin if it "+
+					"is \"-\", where the header names the key attributes (can't be used together with \"%s\", \"%s\" "+
+					"or \"%s\")", hashFlagName, hashFileFlagName, filterFlagName),
+			},
+		}, queryFlags()...),
+			cli.UintFlag{
+				Name: fmt.Sprintf("%s", segmentsFlagName),
+				Usage: "number of segments the scan is split into and run in parallel (or the number of the hashes " +
+					"queried in parallel)",
+				Value: defaultSegments,
+			},
+			cli.Float64Flag{
+				Name:  fmt.Sprintf("%s", rateFlagName),
+				Usage: "max number of items deleted per second, 0 means no limit",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", backupFlagName),
+				Usage: "write the items into the CSV files <backup>-00001.csv, <backup>-00002.csv, etc. (with the " +
+					"manifest) before deleting them, the next file is started once the new attribute is detected",
+			},
+			cli.BoolFlag{
+				Name:  fmt.Sprintf("%s", dryRunFlagName),
+				Usage: "only print the number of the items which would be deleted",
+			},
+			cli.BoolFlag{
+				Name:  fmt.Sprintf("%s", yesFlagName),
+				Usage: "delete without asking for the confirmation",
+			},
+		),
+		Action: deleteItems,
+	}
+}
+
+func deleteItems(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	table := mustFlag(c, tableFlagName)
+	qp, err := queryParams(c)
+	if err != nil {
+		return err
+	}
+	filter, err := dynamodb.ParseFilter(c.StringSlice(filterFlagName))
+	if err != nil {
+		return err
+	}
+	if _, err := readKeys(c, qp, filter); err != nil {
+		return err
+	}
+	sp := sessionParams(c, cfg)
+	var count int64
+	if qp.Keys != nil {
+		count = int64(len(qp.Keys.Values))
+	} else {
+		result, err := dynamodb.CountItems(sp, table, c.String(indexFlagName), qp, filter, c.Uint(segmentsFlagName))
+		if err != nil {
+			return err
+		}
+		count = result.Count
+	}
+	fmt.Printf("%d items to delete from %s\n", count, table)
+	if c.Bool(dryRunFlagName) || count == 0 {
+		return nil
+	}
+	if !c.Bool(yesFlagName) {
+		if err := confirm(c, table); err != nil {
+			return err
+		}
+	}
+	dp := &dynamodb.DeleteParams{
+		Table:    table,
+		Index:    c.String(indexFlagName),
+		Query:    qp,
+		Filter:   filter,
+		Segments: c.Uint(segmentsFlagName),
+		Rate:     c.Float64(rateFlagName),
+	}
+	var backup *output.SplitWriter
+	if filename := c.String(backupFlagName); filename != "" {
+		backup = output.NewSplitWriter(filename, 0, 0)
+		dp.Backup = backup
+	}
+	deleted, err := dynamodb.DeleteItems(sp, dp)
+	fmt.Printf("Deleted %d items\n", deleted)
+	if backup != nil {
+		if closeErr := backup.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// confirm asks to type the table name to confirm the delete.
+func confirm(c *cli.Context, table string) error {
+	if c.String(keysFileFlagName) == "-" || c.String(hashFileFlagName) == "-" {
+		return fmt.Errorf("can't ask for the confirmation while reading stdin, use \"%s\" to confirm", yesFlagName)
+	}
+	fmt.Printf("Type the table name to confirm: ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return err
+	}
+	if strings.TrimSpace(answer) != table {
+		return fmt.Errorf("delete is not confirmed")
+	}
+	return nil
+}
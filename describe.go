@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+)
+
+const (
+	jsonFlagName = "json"
+
+	describeCommandName = "describe"
+)
+
+func describeCommand() cli.Command {
+	return cli.Command{
+		Name:  describeCommandName,
+		Usage: "describe table's key schema, indexes, item count, size, billing mode, stream and TTL settings",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t <table>
+        [--json]`,
+			appName, describeCommandName),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table to describe",
+			},
+			cli.BoolFlag{
+				Name:  fmt.Sprintf("%s", jsonFlagName),
+				Usage: "output the description as JSON",
+			},
+		},
+		Action: describe,
+	}
+}
+
+func describe(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	schema, err := dynamodb.DescribeTable(sessionParams(c, cfg), mustFlag(c, tableFlagName))
+	if err != nil {
+		return err
+	}
+	if c.Bool(jsonFlagName) {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(schema)
+	}
+	return schema.Print(os.Stdout)
+}
@@ -0,0 +1,884 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"github.com/zshamrock/dynocsv/output"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	tableFlagName              = "table"
+	indexFlagName              = "index"
+	columnsFlagName            = "columns"
+	skipColumnsFlagName        = "skip-columns"
+	limitFlagName              = "limit"
+	hashFlagName               = "hash"
+	hashFileFlagName           = "hash-file"
+	concurrencyFlagName        = "concurrency"
+	keysFileFlagName           = "keys-file"
+	missingKeysFlagName        = "missing-keys"
+	descFlagName               = "desc"
+	sortFlagName               = "sort"
+	sortGtFlagName             = "sort-gt"
+	sortGeFlagName             = "sort-ge"
+	sortLtFlagName             = "sort-lt"
+	sortLeFlagName             = "sort-le"
+	sortBeginsWithFlagName     = "sort-begins-with"
+	sortBetweenFlagName        = "sort-between"
+	filterFlagName             = "filter"
+	sinceFlagName              = "since"
+	untilFlagName              = "until"
+	sortFormatFlagName         = "sort-format"
+	outputFlagName             = "output"
+	splitRowsFlagName          = "split-rows"
+	splitSizeFlagName          = "split-size"
+	partitionByFlagName        = "partition-by"
+	maxOpenFilesFlagName       = "max-open-files"
+	entityByFlagName           = "entity-by"
+	entityPatternFlagName      = "entity-pattern"
+	entityKeyColumnsFlagName   = "entity-key-columns"
+	entityKeySeparatorFlagName = "entity-key-separator"
+	groupByFlagName            = "group-by"
+	aggFlagName                = "agg"
+	maxGroupsFlagName          = "max-groups"
+	withSchemaFlagName         = "with-schema"
+	partiqlFlagName            = "partiql"
+	paramFlagName              = "param"
+	incrementalByFlagName      = "incremental-by"
+	appendFlagName             = "append"
+
+	defaultMaxOpenFiles       = 128
+	defaultEntityPattern      = "^([^#]+)#"
+	defaultEntityKeySeparator = "#"
+	defaultAgg                = "count"
+	defaultConcurrency        = 8
+	defaultMaxGroups          = 1000000
+
+	sortBetweenValueSeparator = ","
+	schemaSuffix              = "-schema.json"
+	incrementalSuffix         = "-incremental.json"
+
+	exportCommandName = "export"
+	runCommandName    = "run"
+)
+
+var sortFlags = []string{
+	sortFlagName,
+	sortGtFlagName,
+	sortGeFlagName,
+	sortLtFlagName,
+	sortLeFlagName,
+	sortBeginsWithFlagName,
+	sortBetweenFlagName,
+}
+
+func exportCommand() cli.Command {
+	return cli.Command{
+		Name:  exportCommandName,
+		Usage: "export DynamoDB table into CSV file (default command)",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t                                     <table> 
+        [--columns/-c                                  <comma separated columns>] 
+        [--skip-columns/-sc                            <comma separated columns to skip>] 
+        [--limit/-l                                    <number>]
+        [--index/-i                                    <index to query instead of table>]
+        [--hash                                        <hash value>]
+        [--hash-file                                   <file with hash value per line, or - for stdin>]
+        [--concurrency                                 <number of hash values queried at the same time>]
+        [--keys-file                                   <CSV file with primary keys, or - for stdin>]
+        [--missing-keys                                <CSV file to write keys not found into>]
+        [--partiql                                     <PartiQL statement>]
+        [--param                                       <statement parameter value>]...
+        [--incremental-by                              <attribute, i.e. updatedAt>]
+        [--state                                       <file to keep the incremental export watermark in>]
+        [--sort                                        <sort value>]
+        [--sort-[gt, ge, lt, le, begins-with, between] <sort value>]
+        [--since                                       <duration, i.e. 24h, or time>]
+        [--until                                       <duration, i.e. 24h, or time>]
+        [--sort-format                                 <iso, epoch or epoch-ms>]
+        [--desc]
+        [--filter                                      <attribute><operator><value>]...
+        [--output/-o                                   <output file name>]
+        [--split-rows                                  <number of rows per file>]
+        [--split-size                                  <size per file, i.e. 500MB>]
+        [--partition-by                                <attribute to write separate file per value>]
+        [--max-open-files                              <number>]
+        [--entity-by                                   <attribute to write separate file per entity type>]
+        [--entity-pattern                              <regexp to extract entity type>]
+        [--entity-key-columns                          <comma separated columns>]
+        [--entity-key-separator                        <composite key separator>]
+        [--group-by                                    <comma separated attributes to group by>]
+        [--agg                                         <comma separated aggregations>]
+        [--max-groups                                  <number>]
+        [--append]
+        [--with-schema]`,
+			appName, exportCommandName),
+		Flags:  exportFlags(),
+		Action: exportAction,
+	}
+}
+
+func runCommand() cli.Command {
+	return cli.Command{
+		Name:      runCommandName,
+		Usage:     fmt.Sprintf("run the named preset defined in %s, the flags override the preset values", config.Filename),
+		ArgsUsage: "<preset>",
+		Flags:     exportFlags(),
+		Action:    run,
+	}
+}
+
+// exportFlags returns the flags of the export, shared by the "export" and "run" commands, so they can override the
+// preset values.
+func exportFlags() []cli.Flag {
+	flags := []cli.Flag{
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s, t", tableFlagName),
+			Usage: "table to export",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s, i", indexFlagName),
+			Usage: "index to query if hash/sort are set instead of table (which is default)",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s, c", columnsFlagName),
+			Usage: fmt.Sprintf(
+				"columns to export from the table, if omitted, all columns will be exported "+
+					"(muttaly exclusive with \"%s\")", skipColumnsFlagName),
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s, sc", skipColumnsFlagName),
+			Usage: fmt.Sprintf(
+				"columns skipped from export from the table, if omitted, all columns will be exported "+
+					"(muttaly exclusive with \"%s\")", columnsFlagName),
+		},
+		cli.UintFlag{
+			Name:  fmt.Sprintf("%s, l", limitFlagName),
+			Usage: "limit number of records returned, if not set (i.e. 0) all items are fetched",
+		},
+	}
+	flags = append(flags, queryFlags()...)
+	flags = append(flags, []cli.Flag{
+		cli.BoolFlag{
+			Name: fmt.Sprintf("%s", descFlagName),
+			Usage: fmt.Sprintf("return the query items in the descending order of the sort key, i.e. together with "+
+				"\"%s\" the last items", limitFlagName),
+		},
+		cli.UintFlag{
+			Name:  fmt.Sprintf("%s", concurrencyFlagName),
+			Usage: fmt.Sprintf("max number of hash values queried at the same time (see \"%s\")", hashFileFlagName),
+			Value: defaultConcurrency,
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", keysFileFlagName),
+			Usage: "fetch the items by the full primary keys read from the CSV file, or from stdin if it is \"-\", " +
+				"where the header names the table's key attributes, the items are written in the order of the keys " +
+				"(can't be used together with the query and filter)",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", missingKeysFlagName),
+			Usage: fmt.Sprintf("CSV file to write the keys of the items not found into (see \"%s\")", keysFileFlagName),
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", partiqlFlagName),
+			Usage: "select the items by the PartiQL statement run with ExecuteStatement, i.e. " +
+				"'SELECT * FROM \"orders\" WHERE customerId = ?', the table is taken from the statement if it is not " +
+				"set (can't be used together with the query, keys and filter)",
+		},
+		cli.StringSliceFlag{
+			Name: fmt.Sprintf("%s", paramFlagName),
+			Usage: fmt.Sprintf("value of the next \"?\" parameter of the \"%s\" statement, the type is inferred the "+
+				"same as by the import, or set as the prefix, i.e. \"S:123\", could be repeated", partiqlFlagName),
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", incrementalByFlagName),
+			Usage: fmt.Sprintf("export only the items whose attribute (i.e. updatedAt, either ISO-8601 string or "+
+				"epoch number) is greater than or equal to the greatest one exported by the last run, kept in \"%s\", "+
+				"skipping the items at it already exported, using the sort condition if the query's sort key is the "+
+				"attribute (i.e. \"%s\" with \"%s\" or \"%s\", the index can't be used without them), or the table "+
+				"is scanned with the filter otherwise", stateFlagName,
+				indexFlagName, hashFlagName, hashFileFlagName),
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", stateFlagName),
+			Usage: fmt.Sprintf("file to keep the watermark of the incremental export in (see \"%s\"), updated once "+
+				"the export succeeds, if not set <table>%s is used", incrementalByFlagName, incrementalSuffix),
+		},
+	}...)
+	flags = append(flags, outputFlags()...)
+	return append(flags, cli.BoolFlag{
+		Name: fmt.Sprintf("%s", appendFlagName),
+		Usage: "append to the output file instead of overwriting it, the columns are the ones of its CSV header " +
+			"if it is not empty (can't be used together with the split, partitioned, entity or grouped output)",
+	}, cli.BoolFlag{
+		Name: fmt.Sprintf("%s", withSchemaFlagName),
+		Usage: fmt.Sprintf("write the table schema needed to recreate it (see \"%s\") into <output>%s next to "+
+			"the output", createTableCommandName, schemaSuffix),
+	})
+}
+
+// outputFlags returns the flags setting how the items are written into the CSV outputs, shared by the commands
+// writing the items.
+func outputFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s, o", outputFlagName),
+			Usage: "output file, or the default <table name>.csv will be used",
+		},
+		cli.UintFlag{
+			Name: fmt.Sprintf("%s", splitRowsFlagName),
+			Usage: "split output into multiple files <output name>-00001.csv, <output name>-00002.csv, etc. " +
+				"with at most the specified number of rows each (excluding the header)",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", splitSizeFlagName),
+			Usage: "split output into multiple files <output name>-00001.csv, <output name>-00002.csv, etc. " +
+				"with at most the specified size each, i.e. \"500MB\" (supported units are B, KB, MB and GB)",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", partitionByFlagName),
+			Usage: "write items into the separate file per distinct value of the attribute, i.e. " +
+				"<output>/<attribute>=<value>.csv, where output is the directory (or the default <table name>)",
+		},
+		cli.UintFlag{
+			Name:  fmt.Sprintf("%s", maxOpenFilesFlagName),
+			Usage: fmt.Sprintf("max number of files kept open at the same time while writing partitions (see \"%s\")", partitionByFlagName),
+			Value: defaultMaxOpenFiles,
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", entityByFlagName),
+			Usage: "write items into the separate file per entity type of the single-table design, i.e. " +
+				"<output>/<entity>.csv, where output is the directory (or the default <table name>), and the entity " +
+				"type is extracted from the attribute value",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", entityPatternFlagName),
+			Usage: fmt.Sprintf("regexp to extract the entity type from the \"%s\" attribute value, the first "+
+				"capturing group (or the whole match if there is none) is used", entityByFlagName),
+			Value: defaultEntityPattern,
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", entityKeyColumnsFlagName),
+			Usage: fmt.Sprintf("split the \"%s\" attribute value (i.e. ORDER#2020#123) into the extra columns named "+
+				"by the corresponding position (i.e. \",year,id\"), empty name skips the part", entityByFlagName),
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", entityKeySeparatorFlagName),
+			Usage: fmt.Sprintf("separator of the composite key parts (see \"%s\")", entityKeyColumnsFlagName),
+			Value: defaultEntityKeySeparator,
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", groupByFlagName),
+			Usage: "write the single row per distinct values of the attributes with the aggregations (see \"agg\") " +
+				"instead of the items",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", aggFlagName),
+			Usage: fmt.Sprintf("aggregations of each group (see \"%s\"), one of count, count(<attribute>), "+
+				"sum(<attribute>), min(<attribute>) or max(<attribute>), i.e. \"count,sum(amount),max(ts)\"",
+				groupByFlagName),
+			Value: defaultAgg,
+		},
+		cli.UintFlag{
+			Name: fmt.Sprintf("%s", maxGroupsFlagName),
+			Usage: fmt.Sprintf("max number of groups kept in memory (see \"%s\"), the rest are spilled into the "+
+				"temp files and merged at the end", groupByFlagName),
+			Value: defaultMaxGroups,
+		},
+	}
+}
+
+// queryFlags returns the flags selecting the items by the key conditions and the filter, shared by the commands
+// reading the items.
+func queryFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", hashFlagName),
+			Usage: "limit query by hash value (eq/=)",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", hashFileFlagName),
+			Usage: fmt.Sprintf("query each of the hash values read line by line from the file, or from stdin if "+
+				"it is \"-\", with the same sort condition (muttaly exclusive with \"%s\"), the items are written in "+
+				"the order of the hash values", hashFlagName),
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", sortFlagName),
+			Usage: "limit query by sort value (eq/=)",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", sortGtFlagName),
+			Usage: "limit query by sort value (gt/>)",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", sortGeFlagName),
+			Usage: "limit query by sort value (ge/>=)",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", sortLtFlagName),
+			Usage: "limit query by sort value (lt/<)",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", sortLeFlagName),
+			Usage: "limit query by sort value (le/<=)",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", sortBeginsWithFlagName),
+			Usage: "limit query by sort value (begins with)",
+		},
+		cli.StringFlag{
+			Name:  fmt.Sprintf("%s", sortBetweenFlagName),
+			Usage: "limit query by sort value (between), values are separated by comma, i.e. \"value1,value2\"",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", sinceFlagName),
+			Usage: "limit query by sort key timestamp since (ge/>=) the duration ago, i.e. \"24h\" or \"7d\", or " +
+				"the time, i.e. \"2020-05-01\" or \"2020-05-01T10:00:00Z\"",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", untilFlagName),
+			Usage: "limit query by sort key timestamp until (le/<=) the duration ago, i.e. \"1h\", or the time, " +
+				"i.e. \"2020-05-01\"",
+		},
+		cli.StringFlag{
+			Name: fmt.Sprintf("%s", sortFormatFlagName),
+			Usage: fmt.Sprintf("format of the sort key timestamps (see \"%s\" and \"%s\"), one of %s, if not "+
+				"set, epoch is used for the number sort key, and iso otherwise", sinceFlagName, untilFlagName,
+				strings.Join(dynamodb.SortFormats, ", ")),
+		},
+		cli.StringSliceFlag{
+			Name: fmt.Sprintf("%s", filterFlagName),
+			Usage: "filter items by the attribute condition <attribute><operator><value>, where the operator is one of " +
+				"=, !=, <, <=, >, >=, ^= (begins with) or ~= (contains), i.e. \"status=active\", or by whether the " +
+				"attribute exists (<attribute>) or not (!<attribute>), use <attribute>:S or <attribute>:N to set the " +
+				"value type explicitly, i.e. \"zip:S=02134\", can be repeated (all conditions have to match)",
+		},
+	}
+}
+
+// run runs the export using the preset values for the flags which are not explicitly set.
+func run(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	name := c.Args().First()
+	if name == "" {
+		return fmt.Errorf("preset name is required, i.e. \"%s %s <preset>\"", appName, runCommandName)
+	}
+	preset, err := cfg.Preset(name)
+	if err != nil {
+		return err
+	}
+	for flag, values := range preset {
+		if c.IsSet(flag) || c.GlobalIsSet(flag) {
+			continue
+		}
+		// the list is set as the repeated flag values, AWS connection settings are global flags
+		for _, value := range values {
+			if err := c.Set(flag, value); err != nil {
+				if err := c.GlobalSet(flag, value); err != nil {
+					return fmt.Errorf("invalid preset %q setting %q: %v", name, flag, err)
+				}
+			}
+		}
+	}
+	return export(c, cfg)
+}
+
+func exportAction(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return export(c, cfg)
+}
+
+func export(c *cli.Context, cfg *config.Config) error {
+	table, index := c.String(tableFlagName), c.String(indexFlagName)
+	if statement := c.String(partiqlFlagName); statement != "" {
+		statementTable, statementIndex := dynamodb.StatementTable(statement)
+		if table == "" {
+			table = statementTable
+		}
+		if index == "" && table == statementTable {
+			index = statementIndex
+		}
+	}
+	if table == "" {
+		table = mustFlag(c, tableFlagName)
+	}
+	columns := c.String(columnsFlagName)
+	skipColumns := c.String(skipColumnsFlagName)
+	if columns != "" && skipColumns != "" {
+		fmt.Printf("Both \"%s\" and \"%s\" are provided, they are mutually exclusive, please, use one.\n",
+			columnsFlagName, skipColumnsFlagName)
+		os.Exit(1)
+	}
+	qp, err := queryParams(c)
+	if err != nil {
+		return err
+	}
+	filter, err := dynamodb.ParseFilter(c.StringSlice(filterFlagName))
+	if err != nil {
+		return err
+	}
+	if c.Bool(descFlagName) {
+		if qp.Hash == "" && len(qp.Hashes) == 0 {
+			return fmt.Errorf("\"%s\" requires the query, i.e. \"%s\" or \"%s\"", descFlagName, hashFlagName,
+				hashFileFlagName)
+		}
+		qp.Desc = true
+	}
+	if err := readStatement(c, qp, filter); err != nil {
+		return err
+	}
+	missing, err := readKeys(c, qp, filter)
+	if err != nil {
+		return err
+	}
+	if err := readIncremental(c, table, qp); err != nil {
+		return err
+	}
+	if c.Bool(appendFlagName) {
+		header, err := readHeader(outputFilename(c, table))
+		if err != nil {
+			return err
+		}
+		if header != nil {
+			if columns != "" || skipColumns != "" {
+				return fmt.Errorf("\"%s\" and \"%s\" can't be used when appending to the output with the header",
+					columnsFlagName, skipColumnsFlagName)
+			}
+			columns = strings.Join(header, ",")
+		}
+	}
+	writers, closer, err := openWriters(c, table)
+	if err != nil {
+		return err
+	}
+	if missing != nil {
+		closer = closers{closer, missing}
+	}
+	limit := c.Uint(limitFlagName)
+	sp := sessionParams(c, cfg)
+	if c.Bool(withSchemaFlagName) {
+		if err := writeSchema(sp, table, schemaFilename(c, table)); err != nil {
+			return err
+		}
+	}
+	headers := dynamodb.ExportToCSV(sp, table, index, qp, filter, columns, skipColumns, limit,
+		c.Uint(concurrencyFlagName), writers)
+	printHeaders(c, headers)
+	if err := closer.Close(); err != nil {
+		return err
+	}
+	// the watermark is saved only once the export has succeeded, so the failed one is run again from the same watermark
+	if qp.Incremental != nil {
+		return qp.Incremental.Save()
+	}
+	return nil
+}
+
+// printHeaders prints the CSV headers of the outputs which got new attributes detected after the CSV headers have been
+// already written.
+func printHeaders(c *cli.Context, headers map[string][]string) {
+	// split output rolls over into the next file once the new attribute is detected, so each file has the proper header,
+	// and grouped output has its own header
+	if c.String(columnsFlagName) != "" || isSplit(c) || c.String(groupByFlagName) != "" {
+		return
+	}
+	for key, attributes := range headers {
+		if c.String(entityByFlagName) != "" {
+			fmt.Printf("%s: ", key)
+		} else if key != "" {
+			fmt.Printf("%s=%s: ", c.String(partitionByFlagName), key)
+		}
+		fmt.Println(strings.Join(attributes, ","))
+	}
+}
+
+// queryParams returns the key conditions, the sort condition is used only together with the hash one.
+func queryParams(c *cli.Context) (*dynamodb.QueryParams, error) {
+	hash := c.String(hashFlagName)
+	qp := &dynamodb.QueryParams{Hash: hash}
+	if hashFile := c.String(hashFileFlagName); hashFile != "" {
+		if hash != "" {
+			return nil, fmt.Errorf("both \"%s\" and \"%s\" are provided, they are mutually exclusive, please, use one",
+				hashFlagName, hashFileFlagName)
+		}
+		hashes, err := readHashes(hashFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(hashes) == 0 {
+			return nil, fmt.Errorf("no hash values found in %s", hashFile)
+		}
+		qp.Hashes = hashes
+	}
+	if hash != "" || len(qp.Hashes) != 0 {
+		setSortFlags := make([]string, 0)
+		for _, flag := range sortFlags {
+			if c.String(flag) != "" {
+				setSortFlags = append(setSortFlags, flag)
+			}
+		}
+		for _, flag := range []string{sinceFlagName, untilFlagName} {
+			if c.String(flag) != "" && len(setSortFlags) != 0 {
+				return nil, fmt.Errorf("\"%s\" can't be used together with %v", flag, setSortFlags)
+			}
+		}
+		if len(setSortFlags) > 1 {
+			return nil, fmt.Errorf(
+				"only single sort condition is supported, but found %d: %v", len(setSortFlags), setSortFlags)
+		}
+		if len(setSortFlags) != 0 {
+			sortFlag := setSortFlags[0]
+			switch sort := c.String(sortFlag); sortFlag {
+			case sortFlagName:
+				qp.Sort = sort
+			case sortGtFlagName:
+				qp.SortGt = sort
+			case sortGeFlagName:
+				qp.SortGe = sort
+			case sortLtFlagName:
+				qp.SortLt = sort
+			case sortLeFlagName:
+				qp.SortLe = sort
+			case sortBeginsWithFlagName:
+				qp.SortBeginsWith = sort
+			case sortBetweenFlagName:
+				qp.SortBetween = strings.Split(sort, sortBetweenValueSeparator)
+			}
+		}
+		if err := setTimeRange(c, qp); err != nil {
+			return nil, err
+		}
+	}
+	return qp, nil
+}
+
+// setTimeRange sets the sort key time range, which is translated into the corresponding sort condition.
+func setTimeRange(c *cli.Context, qp *dynamodb.QueryParams) error {
+	now := time.Now()
+	for _, flag := range []string{sinceFlagName, untilFlagName} {
+		value := c.String(flag)
+		if value == "" {
+			continue
+		}
+		t, err := dynamodb.ParseTime(value, now)
+		if err != nil {
+			return err
+		}
+		if flag == sinceFlagName {
+			qp.Since = &t
+		} else {
+			qp.Until = &t
+		}
+	}
+	if format := c.String(sortFormatFlagName); format != "" {
+		valid := false
+		for _, f := range dynamodb.SortFormats {
+			valid = valid || f == format
+		}
+		if !valid {
+			return fmt.Errorf("unsupported \"%s\" %q, supported are %s",
+				sortFormatFlagName, format, strings.Join(dynamodb.SortFormats, ", "))
+		}
+		qp.SortFormat = format
+	}
+	return nil
+}
+
+// readKeys sets the keys of the items to fetch if the keys file is set, and returns the file the missing keys are
+// written into if it is set.
+func readKeys(c *cli.Context, qp *dynamodb.QueryParams, filter dynamodb.Filter) (io.Closer, error) {
+	keysFile := c.String(keysFileFlagName)
+	if keysFile == "" {
+		return nil, nil
+	}
+	if qp.Hash != "" || len(qp.Hashes) != 0 || len(filter) != 0 {
+		return nil, fmt.Errorf("\"%s\" can't be used together with \"%s\", \"%s\" or \"%s\"",
+			keysFileFlagName, hashFlagName, hashFileFlagName, filterFlagName)
+	}
+	var r io.Reader = os.Stdin
+	if keysFile != "-" {
+		file, err := os.Open(keysFile)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	keys, err := dynamodb.ReadKeys(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys from %s: %v", keysFile, err)
+	}
+	qp.Keys = keys
+	missingKeys := c.String(missingKeysFlagName)
+	if missingKeys == "" {
+		return nil, nil
+	}
+	file, err := os.OpenFile(missingKeys, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	keys.Missing = output.NewCSVWriter(bufio.NewWriter(file))
+	return file, nil
+}
+
+// readStatement sets the PartiQL statement selecting the items if it is set.
+func readStatement(c *cli.Context, qp *dynamodb.QueryParams, filter dynamodb.Filter) error {
+	statement := c.String(partiqlFlagName)
+	if statement == "" {
+		if len(c.StringSlice(paramFlagName)) != 0 {
+			return fmt.Errorf("\"%s\" requires \"%s\"", paramFlagName, partiqlFlagName)
+		}
+		return nil
+	}
+	if qp.Hash != "" || len(qp.Hashes) != 0 || c.String(keysFileFlagName) != "" || len(filter) != 0 {
+		return fmt.Errorf("\"%s\" can't be used together with \"%s\", \"%s\", \"%s\" or \"%s\", use the WHERE "+
+			"clause instead", partiqlFlagName, hashFlagName, hashFileFlagName, keysFileFlagName, filterFlagName)
+	}
+	st, err := dynamodb.ParseStatement(statement, c.StringSlice(paramFlagName))
+	if err != nil {
+		return err
+	}
+	qp.Statement = st
+	return nil
+}
+
+// readIncremental loads the state of the incremental export if it is set.
+func readIncremental(c *cli.Context, table string, qp *dynamodb.QueryParams) error {
+	attribute := c.String(incrementalByFlagName)
+	if attribute == "" {
+		if c.String(stateFlagName) != "" {
+			return fmt.Errorf("\"%s\" requires \"%s\"", stateFlagName, incrementalByFlagName)
+		}
+		return nil
+	}
+	if qp.Keys != nil || qp.Statement != nil || c.Uint(limitFlagName) != 0 {
+		return fmt.Errorf("\"%s\" can't be used together with \"%s\", \"%s\" or \"%s\"", incrementalByFlagName,
+			keysFileFlagName, partiqlFlagName, limitFlagName)
+	}
+	// the index is only queried, so without the hash value the whole table would be scanned rather than the index
+	if c.String(indexFlagName) != "" && qp.Hash == "" && len(qp.Hashes) == 0 {
+		return fmt.Errorf("\"%s\" together with \"%s\" requires \"%s\" or \"%s\", otherwise drop \"%s\" to "+
+			"scan the table with the filter", incrementalByFlagName, indexFlagName, hashFlagName, hashFileFlagName,
+			indexFlagName)
+	}
+	path := c.String(stateFlagName)
+	if path == "" {
+		path = table + incrementalSuffix
+	}
+	incremental, err := dynamodb.LoadIncremental(path, table, attribute)
+	if err != nil {
+		return err
+	}
+	qp.Incremental = incremental
+	return nil
+}
+
+// readHashes reads the non blank lines of the file, or of stdin if the path is "-".
+func readHashes(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	hashes := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if hash := strings.TrimSpace(scanner.Text()); hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, scanner.Err()
+}
+
+// schemaFilename returns the name of the table schema file written next to the output, i.e. "orders-schema.json" for
+// "orders.csv".
+func schemaFilename(c *cli.Context, table string) string {
+	filename := c.String(outputFlagName)
+	if filename == "" {
+		filename = table
+	}
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + schemaSuffix
+}
+
+func writeSchema(sp *awssessions.SessionParams, table string, filename string) error {
+	schema, err := dynamodb.DescribeTable(sp, table)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0666)
+}
+
+func isSplit(c *cli.Context) bool {
+	return c.Uint(splitRowsFlagName) > 0 || c.String(splitSizeFlagName) != ""
+}
+
+// openWriters opens the outputs the items are exported into, depending on whether the output should be split or
+// partitioned.
+func openWriters(c *cli.Context, table string) (dynamodb.Writers, io.Closer, error) {
+	filename := c.String(outputFlagName)
+	if c.Bool(appendFlagName) && (isSplit(c) || c.String(partitionByFlagName) != "" ||
+		c.String(entityByFlagName) != "" || c.String(groupByFlagName) != "") {
+
+		return nil, nil, fmt.Errorf("\"%s\" can't be used together with \"%s\", \"%s\", \"%s\", \"%s\" or \"%s\"",
+			appendFlagName, splitRowsFlagName, splitSizeFlagName, partitionByFlagName, entityByFlagName,
+			groupByFlagName)
+	}
+	if entityBy := c.String(entityByFlagName); entityBy != "" {
+		if isSplit(c) || c.String(partitionByFlagName) != "" || c.String(groupByFlagName) != "" {
+			return nil, nil, fmt.Errorf("\"%s\" can't be used together with \"%s\", \"%s\", \"%s\" or \"%s\"",
+				entityByFlagName, partitionByFlagName, splitRowsFlagName, splitSizeFlagName, groupByFlagName)
+		}
+		pattern, err := regexp.Compile(c.String(entityPatternFlagName))
+		if err != nil {
+			return nil, nil, err
+		}
+		params := dynamodb.EntityParams{
+			Attribute:    entityBy,
+			Pattern:      pattern,
+			KeySeparator: c.String(entityKeySeparatorFlagName),
+		}
+		if keyColumns := c.String(entityKeyColumnsFlagName); keyColumns != "" {
+			params.KeyColumns = strings.Split(keyColumns, ",")
+		}
+		if filename == "" {
+			filename = table
+		}
+		files := output.NewFiles(int(c.Uint(maxOpenFilesFlagName)))
+		return dynamodb.EntityWriters(params, filename, files), files, nil
+	}
+	if partitionBy := c.String(partitionByFlagName); partitionBy != "" {
+		if isSplit(c) || c.String(groupByFlagName) != "" {
+			return nil, nil, fmt.Errorf("\"%s\" can't be used together with \"%s\", \"%s\" or \"%s\"",
+				partitionByFlagName, splitRowsFlagName, splitSizeFlagName, groupByFlagName)
+		}
+		if filename == "" {
+			filename = table
+		}
+		files := output.NewFiles(int(c.Uint(maxOpenFilesFlagName)))
+		return dynamodb.PartitionWriters(partitionBy, filename, files), files, nil
+	}
+	filename = outputFilename(c, table)
+	if groupBy := c.String(groupByFlagName); groupBy != "" {
+		if isSplit(c) {
+			return nil, nil, fmt.Errorf("\"%s\" can't be used together with \"%s\" or \"%s\"",
+				groupByFlagName, splitRowsFlagName, splitSizeFlagName)
+		}
+		attributes := strings.Split(groupBy, ",")
+		aggregations, err := output.ParseAggregations(c.String(aggFlagName))
+		if err != nil {
+			return nil, nil, err
+		}
+		if columns := c.String(columnsFlagName); columns != "" {
+			if err := checkGroupColumns(strings.Split(columns, ","), attributes, aggregations); err != nil {
+				return nil, nil, err
+			}
+		}
+		file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+		if err != nil {
+			return nil, nil, err
+		}
+		writer := output.NewGroupWriter(
+			output.NewCSVWriter(bufio.NewWriter(file)), attributes, aggregations, int(c.Uint(maxGroupsFlagName)))
+		return dynamodb.SingleWriter(writer), closers{writer, file}, nil
+	}
+	if isSplit(c) {
+		var size int64
+		if s := c.String(splitSizeFlagName); s != "" {
+			var err error
+			size, err = output.ParseSize(s)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		writer := output.NewSplitWriter(filename, c.Uint(splitRowsFlagName), size)
+		return dynamodb.SingleWriter(writer), writer, nil
+	}
+	if !c.Bool(appendFlagName) {
+		file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+		if err != nil {
+			return nil, nil, err
+		}
+		return dynamodb.SingleWriter(output.NewCSVWriter(bufio.NewWriter(file))), file, nil
+	}
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if info.Size() != 0 {
+		return dynamodb.SingleWriter(output.NewAppendCSVWriter(bufio.NewWriter(file))), file, nil
+	}
+	return dynamodb.SingleWriter(output.NewCSVWriter(bufio.NewWriter(file))), file, nil
+}
+
+// outputFilename returns the name of the single output file, i.e. "orders.csv" if it is not set.
+func outputFilename(c *cli.Context, table string) string {
+	if filename := c.String(outputFlagName); filename != "" {
+		return filename
+	}
+	return fmt.Sprintf("%s.csv", table)
+}
+
+// checkGroupColumns checks the columns exported include all the attributes the groups are built from.
+func checkGroupColumns(columns []string, groupBy []string, aggregations []output.Aggregation) error {
+	set := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		set[column] = true
+	}
+	attributes := append([]string{}, groupBy...)
+	for _, a := range aggregations {
+		if a.Attribute != "" {
+			attributes = append(attributes, a.Attribute)
+		}
+	}
+	for _, attribute := range attributes {
+		if !set[attribute] {
+			return fmt.Errorf("attribute %q used by \"%s\" or \"%s\" is not in \"%s\"",
+				attribute, groupByFlagName, aggFlagName, columnsFlagName)
+		}
+	}
+	return nil
+}
+
+// closers closes all the closers in order, and returns the first error.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var err error
+	for _, closer := range cs {
+		if e := closer.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -3,8 +3,8 @@ module github.com/zshamrock/dynocsv
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.26.7
+	github.com/aws/aws-sdk-go v1.37.0
 	github.com/stretchr/testify v1.4.0 // indirect
-	golang.org/x/net v0.0.0-20191209160850-c0dbc17a3553 // indirect
 	gopkg.in/urfave/cli.v1 v1.20.0
+	gopkg.in/yaml.v2 v2.2.8
 )
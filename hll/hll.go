@@ -0,0 +1,65 @@
+package hll
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+const (
+	precision = 14
+	registers = 1 << precision
+)
+
+// Sketch is the HyperLogLog sketch estimating the number of distinct values added, with ~0.8% standard error.
+type Sketch struct {
+	registers []uint8
+}
+
+// New returns the empty sketch.
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, registers)}
+}
+
+// Add adds the value into the sketch.
+func (s *Sketch) Add(value string) {
+	h := hash(value)
+	index := h >> (64 - precision)
+	rank := uint8(bits.LeadingZeros64(h<<precision|1<<(precision-1)) + 1)
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Estimate returns the estimated number of distinct values added.
+func (s *Sketch) Estimate() uint64 {
+	sum := 0.0
+	zeros := 0
+	for _, r := range s.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	m := float64(registers)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros != 0 {
+		// small range correction, i.e. linear counting
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// FNV-1a is fast, but its bits are not uniformly distributed enough, so they are additionally mixed (splitmix64
+// finalizer).
+func hash(value string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(value))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,36 @@
+package hll
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestSketchEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		distinct int
+		repeat   int
+	}{
+		{name: "empty", distinct: 0, repeat: 1},
+		{name: "single value", distinct: 1, repeat: 10},
+		{name: "small cardinality", distinct: 100, repeat: 3},
+		{name: "medium cardinality", distinct: 10000, repeat: 2},
+		{name: "large cardinality", distinct: 200000, repeat: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for r := 0; r < tt.repeat; r++ {
+				for i := 0; i < tt.distinct; i++ {
+					s.Add("value" + strconv.Itoa(i))
+				}
+			}
+			got := float64(s.Estimate())
+			// allow 3 standard errors
+			if math.Abs(got-float64(tt.distinct)) > math.Max(1, 0.03*float64(tt.distinct)) {
+				t.Errorf("Estimate() = %v, want %v", got, tt.distinct)
+			}
+		})
+	}
+}
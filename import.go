@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+)
+
+const (
+	inputFlagName = "input"
+	typesFlagName = "types"
+	rateFlagName  = "rate"
+
+	importCommandName = "import"
+)
+
+func importCommand() cli.Command {
+	return cli.Command{
+		Name:  importCommandName,
+		Usage: "import CSV into the table using BatchWriteItem, the header names the attributes",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t <table>
+        --input/-i <CSV file or "-" for stdin>
+        [--types   <attribute>=<type>[,<attribute>=<type>]...]
+        [--rate    <max items written per second>]`,
+			appName, importCommandName),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table to import items into",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, i", inputFlagName),
+				Usage: "CSV file to import, or \"-\" to read it from stdin",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", typesFlagName),
+				Usage: "attribute types, i.e. \"age=N,tags=SS\" (one of S, N, B, BOOL, NULL, SS, NS, BS, L or M), " +
+					"otherwise the key attributes types are taken from the table, and the rest are inferred from " +
+					"the values",
+			},
+			cli.Float64Flag{
+				Name:  fmt.Sprintf("%s", rateFlagName),
+				Usage: "max number of items written per second, 0 means no limit",
+			},
+		},
+		Action: importCSV,
+	}
+}
+
+func importCSV(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	types, err := dynamodb.ParseTypes(c.String(typesFlagName))
+	if err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if input := mustFlag(c, inputFlagName); input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	written, err := dynamodb.ImportFromCSV(sessionParams(c, cfg), mustFlag(c, tableFlagName), r, types,
+		c.Float64(rateFlagName))
+	fmt.Printf("Imported %d items\n", written)
+	return err
+}
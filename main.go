@@ -1,45 +1,24 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	awssessions "github.com/zshamrock/dynocsv/aws"
+	"github.com/zshamrock/dynocsv/config"
 	"gopkg.in/urfave/cli.v1"
 	"log"
 	"os"
-	"strings"
 )
 
 const (
-	tableFlagName          = "table"
-	indexFlagName          = "index"
-	columnsFlagName        = "columns"
-	skipColumnsFlagName    = "skip-columns"
-	limitFlagName          = "limit"
-	profileFlagName        = "profile"
-	hashFlagName           = "hash"
-	sortFlagName           = "sort"
-	sortGtFlagName         = "sort-gt"
-	sortGeFlagName         = "sort-ge"
-	sortLtFlagName         = "sort-lt"
-	sortLeFlagName         = "sort-le"
-	sortBeginsWithFlagName = "sort-begins-with"
-	sortBetweenFlagName    = "sort-between"
-	outputFlagName         = "output"
-
-	sortBetweenValueSeparator = ","
+	profileFlagName         = "profile"
+	regionFlagName          = "region"
+	endpointURLFlagName     = "endpoint-url"
+	roleARNFlagName         = "role-arn"
+	externalIDFlagName      = "external-id"
+	mfaSerialFlagName       = "mfa-serial"
+	sessionDurationFlagName = "session-duration"
 )
 
-var sortFlags = []string{
-	sortFlagName,
-	sortGtFlagName,
-	sortGeFlagName,
-	sortLtFlagName,
-	sortLeFlagName,
-	sortBeginsWithFlagName,
-	sortBetweenFlagName,
-}
-
 const (
 	appName = "dynocsv"
 	version = "1.1.4"
@@ -52,90 +31,70 @@ func main() {
 	app.Version = version
 	app.Author = "(c) Aliaksandr Kazlou"
 	app.Metadata = map[string]interface{}{"GitHub": "https://github.com/zshamrock/dynocsv"}
-	app.UsageText = fmt.Sprintf(`%s		 
-        --table/-t                                     <table> 
-        [--columns/-c                                  <comma separated columns>] 
-        [--skip-columns/-sc                            <comma separated columns to skip>] 
-        [--limit/-l                                    <number>]
-        [--profile/-p                                  <AWS profile>]
-        [--index/-i                                    <index to query instead of table>]
-        [--hash                                        <hash value>]
-        [--sort                                        <sort value>]
-        [--sort-[gt, ge, lt, le, begins-with, between] <sort value>]
-        [--output/-o                                   <output file name>]`,
+	app.UsageText = fmt.Sprintf(`%[1]s [global options] command [command options] [arguments...]
+   %[1]s [global options] --table/-t <table> [export options] (same as "%[1]s export")`,
 		appName)
-	app.Flags = []cli.Flag{
-		cli.StringFlag{
-			Name:  fmt.Sprintf("%s, t", tableFlagName),
-			Usage: "table to export",
-		},
-		cli.StringFlag{
-			Name:  fmt.Sprintf("%s, i", indexFlagName),
-			Usage: "index to query if hash/sort are set instead of table (which is default)",
-		},
-		cli.StringFlag{
-			Name: fmt.Sprintf("%s, c", columnsFlagName),
-			Usage: fmt.Sprintf(
-				"columns to export from the table, if omitted, all columns will be exported "+
-					"(muttaly exclusive with \"%s\")", skipColumnsFlagName),
-		},
-		cli.StringFlag{
-			Name: fmt.Sprintf("%s, sc", skipColumnsFlagName),
-			Usage: fmt.Sprintf(
-				"columns skipped from export from the table, if omitted, all columns will be exported "+
-					"(muttaly exclusive with \"%s\")", columnsFlagName),
-		},
-		cli.UintFlag{
-			Name:  fmt.Sprintf("%s, l", limitFlagName),
-			Usage: "limit number of records returned, if not set (i.e. 0) all items are fetched",
-		},
+	// export flags are also accepted without the command for the backward compatibility, i.e. "dynocsv -t <table>"
+	app.Flags = append(globalFlags(), hidden(exportFlags())...)
+	app.Commands = []cli.Command{
+		exportCommand(),
+		runCommand(),
+		describeCommand(),
+		profileCommand(),
+		countCommand(),
+		importCommand(),
+		copyCommand(),
+		createTableCommand(),
+		deleteCommand(),
+		updateCommand(),
+		validateCommand(),
+		convertCommand(),
+		tailCommand(),
+	}
+	app.Action = action
+
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Panicf("error encountered while running the app %v", err)
+	}
+}
+
+// globalFlags returns the flags shared by all the commands, i.e. AWS connection settings.
+func globalFlags() []cli.Flag {
+	return []cli.Flag{
 		cli.StringFlag{
 			Name: fmt.Sprintf("%s, p", profileFlagName),
 			Usage: "AWS profile to use to connect to DynamoDB, otherwise the value from AWS_PROFILE env var is used " +
 				"if available, or then \"default\" if it is not set or empty",
 		},
 		cli.StringFlag{
-			Name:  fmt.Sprintf("%s", hashFlagName),
-			Usage: "limit query by hash value (eq/=)",
-		},
-		cli.StringFlag{
-			Name:  fmt.Sprintf("%s", sortFlagName),
-			Usage: "limit query by sort value (eq/=)",
+			Name: fmt.Sprintf("%s, r", regionFlagName),
+			Usage: "AWS region to connect to, otherwise the value from AWS_REGION env var is used if available, " +
+				"or then the region of the AWS profile",
 		},
 		cli.StringFlag{
-			Name:  fmt.Sprintf("%s", sortGtFlagName),
-			Usage: "limit query by sort value (gt/>)",
+			Name: fmt.Sprintf("%s", endpointURLFlagName),
+			Usage: "DynamoDB endpoint URL to connect to (i.e. DynamoDB Local or LocalStack), otherwise the value from " +
+				"AWS_ENDPOINT_URL_DYNAMODB or AWS_ENDPOINT_URL env vars is used if available",
 		},
 		cli.StringFlag{
-			Name:  fmt.Sprintf("%s", sortGeFlagName),
-			Usage: "limit query by sort value (ge/>=)",
+			Name:  fmt.Sprintf("%s", roleARNFlagName),
+			Usage: "role to assume using the AWS profile credentials",
 		},
 		cli.StringFlag{
-			Name:  fmt.Sprintf("%s", sortLtFlagName),
-			Usage: "limit query by sort value (lt/<)",
+			Name:  fmt.Sprintf("%s", externalIDFlagName),
+			Usage: fmt.Sprintf("external id to pass while assuming the role (see \"%s\")", roleARNFlagName),
 		},
 		cli.StringFlag{
-			Name:  fmt.Sprintf("%s", sortLeFlagName),
-			Usage: "limit query by sort value (le/<=)",
+			Name: fmt.Sprintf("%s", mfaSerialFlagName),
+			Usage: fmt.Sprintf("serial number (or ARN) of the MFA device required to assume the role "+
+				"(see \"%s\"), the MFA token code is prompted on start", roleARNFlagName),
 		},
-		cli.StringFlag{
-			Name:  fmt.Sprintf("%s", sortBeginsWithFlagName),
-			Usage: "limit query by sort value (begins with)",
-		},
-		cli.StringFlag{
-			Name:  fmt.Sprintf("%s", sortBetweenFlagName),
-			Usage: "limit query by sort value (between), values are separated by comma, i.e. \"value1,value2\"",
+		cli.DurationFlag{
+			Name: fmt.Sprintf("%s", sessionDurationFlagName),
+			Usage: "duration of the assumed role session, i.e. \"1h\" (15m if not set), the credentials are refreshed " +
+				"automatically if the export runs longer",
 		},
-		cli.StringFlag{
-			Name:  fmt.Sprintf("%s, o", outputFlagName),
-			Usage: "output file, or the default <table name>.csv will be used",
-		},
-	}
-	app.Action = action
-
-	err := app.Run(os.Args)
-	if err != nil {
-		log.Panicf("error encountered while running the app %v", err)
 	}
 }
 
@@ -143,63 +102,49 @@ func action(c *cli.Context) error {
 	if len(os.Args) == 1 {
 		cli.ShowAppHelpAndExit(c, 0)
 	}
-	table := mustFlag(c, tableFlagName)
-	columns := c.String(columnsFlagName)
-	skipColumns := c.String(skipColumnsFlagName)
-	if columns != "" && skipColumns != "" {
-		fmt.Printf("Both \"%s\" and \"%s\" are provided, they are mutually exclusive, please, use one.\n",
-			columnsFlagName, skipColumnsFlagName)
-		os.Exit(1)
-	}
-	filename := c.String(outputFlagName)
-	if filename == "" {
-		filename = fmt.Sprintf("%s.csv", table)
+	return exportAction(c)
+}
+
+// sessionParams returns the AWS connection settings, the global flags which are not explicitly set fallback to the
+// config defaults.
+func sessionParams(c *cli.Context, cfg *config.Config) *awssessions.SessionParams {
+	return &awssessions.SessionParams{
+		Profile:         globalStringOrDefault(c, profileFlagName, cfg.Profile),
+		Region:          globalStringOrDefault(c, regionFlagName, cfg.Region),
+		EndpointURL:     globalStringOrDefault(c, endpointURLFlagName, cfg.EndpointURL),
+		RoleARN:         c.GlobalString(roleARNFlagName),
+		ExternalID:      c.GlobalString(externalIDFlagName),
+		MFASerial:       c.GlobalString(mfaSerialFlagName),
+		SessionDuration: c.GlobalDuration(sessionDurationFlagName),
 	}
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-	if err != nil {
-		return err
+}
+
+func globalStringOrDefault(c *cli.Context, name string, value string) string {
+	if c.GlobalIsSet(name) {
+		return c.GlobalString(name)
 	}
-	limit := c.Uint(limitFlagName)
-	profile := c.String(profileFlagName)
-	hash := c.String(hashFlagName)
-	qp := &dynamodb.QueryParams{}
-	if hash != "" {
-		qp.Hash = hash
-		setSortFlags := make([]string, 0)
-		for _, flag := range sortFlags {
-			if c.String(flag) != "" {
-				setSortFlags = append(setSortFlags, flag)
-			}
-		}
-		if len(setSortFlags) > 1 {
-			return fmt.Errorf("only single sort condition is supported, but found %d: %v", len(setSortFlags), setSortFlags)
-		}
-		if len(setSortFlags) != 0 {
-			sortFlag := setSortFlags[0]
-			switch sort := c.String(sortFlag); sortFlag {
-			case sortFlagName:
-				qp.Sort = sort
-			case sortGtFlagName:
-				qp.SortGt = sort
-			case sortGeFlagName:
-				qp.SortGe = sort
-			case sortLtFlagName:
-				qp.SortLt = sort
-			case sortLeFlagName:
-				qp.SortLe = sort
-			case sortBeginsWithFlagName:
-				qp.SortBeginsWith = sort
-			case sortBetweenFlagName:
-				qp.SortBetween = strings.Split(sort, sortBetweenValueSeparator)
-			}
+	return value
+}
+
+// hidden hides the flags from the help.
+func hidden(flags []cli.Flag) []cli.Flag {
+	for i, flag := range flags {
+		switch f := flag.(type) {
+		case cli.StringFlag:
+			f.Hidden = true
+			flags[i] = f
+		case cli.UintFlag:
+			f.Hidden = true
+			flags[i] = f
+		case cli.BoolFlag:
+			f.Hidden = true
+			flags[i] = f
+		case cli.StringSliceFlag:
+			f.Hidden = true
+			flags[i] = f
 		}
 	}
-	headers, force := dynamodb.ExportToCSV(
-		profile, table, c.String(indexFlagName), qp, columns, skipColumns, limit, bufio.NewWriter(file))
-	if columns == "" && force {
-		fmt.Println(strings.Join(headers, ","))
-	}
-	return file.Close()
+	return flags
 }
 
 func mustFlag(c *cli.Context, name string) string {
@@ -0,0 +1,120 @@
+package output
+
+import (
+	"container/list"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+)
+
+// Files keeps at most max files open at the same time. Once the limit is reached, the least recently used file is
+// closed to open the next one, and it is reopened in the append mode on the next write into it.
+type Files struct {
+	max  int
+	open *list.List
+}
+
+// NewFiles returns the files keeping at most max of them open at the same time.
+func NewFiles(max int) *Files {
+	if max < 1 {
+		max = 1
+	}
+	return &Files{max: max, open: list.New()}
+}
+
+// Writer returns the writer of the file, the file is created (or truncated) on the first write.
+func (f *Files) Writer(name string) *FileWriter {
+	return &FileWriter{name: name, files: f}
+}
+
+// Close closes all currently open files.
+func (f *Files) Close() error {
+	var err error
+	for f.open.Len() != 0 {
+		if e := f.open.Front().Value.(*FileWriter).close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (f *Files) acquire(w *FileWriter) error {
+	if w.element != nil {
+		f.open.MoveToFront(w.element)
+		return nil
+	}
+	for f.open.Len() >= f.max {
+		if err := f.open.Back().Value.(*FileWriter).close(); err != nil {
+			return err
+		}
+	}
+	flag := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !w.created {
+		if err := os.MkdirAll(filepath.Dir(w.name), 0777); err != nil {
+			return err
+		}
+		flag |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(w.name, flag, 0666)
+	if err != nil {
+		return err
+	}
+	w.created = true
+	w.file = file
+	w.writer = csv.NewWriter(file)
+	w.element = f.open.PushFront(w)
+	return nil
+}
+
+// FileWriter writes CSV records into the file managed by Files.
+type FileWriter struct {
+	name          string
+	files         *Files
+	created       bool
+	headerWritten bool
+	file          *os.File
+	writer        *csv.Writer
+	element       *list.Element
+}
+
+// Name returns the name of the file.
+func (w *FileWriter) Name() string {
+	return w.name
+}
+
+// WriteHeader writes the header only once, as there is no way to update the header already written into the file.
+func (w *FileWriter) WriteHeader(attributes []string) error {
+	if w.headerWritten {
+		return nil
+	}
+	w.headerWritten = true
+	return w.Write(attributes)
+}
+
+func (w *FileWriter) Write(record []string) error {
+	if err := w.files.acquire(w); err != nil {
+		return err
+	}
+	return w.writer.Write(record)
+}
+
+// Flush writes any buffered data into the file if it is open.
+func (w *FileWriter) Flush() error {
+	if w.writer == nil {
+		return nil
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *FileWriter) close() error {
+	err := w.Flush()
+	w.files.open.Remove(w.element)
+	w.element = nil
+	w.writer = nil
+	if e := w.file.Close(); e != nil && err == nil {
+		err = e
+	}
+	w.file = nil
+	return err
+}
@@ -0,0 +1,32 @@
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesEvictAndReopenInAppendMode(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "dynocsv")
+	defer os.RemoveAll(dir)
+	files := NewFiles(1)
+	a := files.Writer(filepath.Join(dir, "a", "a.csv"))
+	b := files.Writer(filepath.Join(dir, "b.csv"))
+	_ = a.WriteHeader([]string{"A"})
+	_ = a.Write([]string{"1"})
+	_ = b.WriteHeader([]string{"B"})
+	if files.open.Len() != 1 {
+		t.Errorf("open files = %d, want 1", files.open.Len())
+	}
+	_ = b.Write([]string{"2"})
+	_ = a.WriteHeader([]string{"A", "C"})
+	_ = a.Write([]string{"3", "c"})
+	if err := files.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	assertFiles(t, dir, map[string]string{
+		filepath.Join("a", "a.csv"): "A\n1\n3,c\n",
+		"b.csv":                     "B\n2\n",
+	})
+}
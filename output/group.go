@@ -0,0 +1,529 @@
+package output
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AggregationCount counts the rows of the group, or the rows which have the attribute value if it is set.
+	AggregationCount = "count"
+	// AggregationSum sums the number values of the attribute.
+	AggregationSum = "sum"
+	// AggregationMin returns the min value of the attribute, numbers are compared as numbers, anything else as strings.
+	AggregationMin = "min"
+	// AggregationMax returns the max value of the attribute, numbers are compared as numbers, anything else as strings.
+	AggregationMax = "max"
+
+	aggregationsSeparator = ","
+	groupKeySeparator     = "\x00"
+	spillFileFormat       = "group-%05d.csv"
+	// number of the fields each aggregation state is spilled as, see accumulator.fields
+	accumulatorFields = 7
+)
+
+var aggregationPattern = regexp.MustCompile(`^(count|sum|min|max)(?:\((.+)\))?$`)
+
+// Aggregation represents the aggregate function applied to the attribute values of each group, i.e. "sum(amount)".
+type Aggregation struct {
+	Function  string
+	Attribute string
+}
+
+func (a Aggregation) String() string {
+	if a.Attribute == "" {
+		return a.Function
+	}
+	return fmt.Sprintf("%s(%s)", a.Function, a.Attribute)
+}
+
+// ParseAggregations parses the comma separated aggregations, i.e. "count,sum(amount),min(ts),max(ts)".
+func ParseAggregations(aggregations string) ([]Aggregation, error) {
+	parsed := make([]Aggregation, 0)
+	for _, aggregation := range strings.Split(aggregations, aggregationsSeparator) {
+		aggregation = strings.TrimSpace(aggregation)
+		match := aggregationPattern.FindStringSubmatch(aggregation)
+		if match == nil {
+			return nil, fmt.Errorf("unsupported aggregation %q, supported are count, count(<attribute>), "+
+				"sum(<attribute>), min(<attribute>) and max(<attribute>)", aggregation)
+		}
+		a := Aggregation{Function: match[1], Attribute: strings.TrimSpace(match[2])}
+		if a.Function != AggregationCount && a.Attribute == "" {
+			return nil, fmt.Errorf("aggregation %q requires the attribute, i.e. %s(<attribute>)", aggregation, a.Function)
+		}
+		parsed = append(parsed, a)
+	}
+	return parsed, nil
+}
+
+// accumulator keeps the state of the single aggregation of the group. All the aggregate functions share the same
+// state, so the states of the same group spilled to the different files can be merged.
+type accumulator struct {
+	count      int64
+	sum        *big.Rat
+	min        string
+	max        string
+	minNumber  *big.Rat
+	maxNumber  *big.Rat
+	nonNumeric bool
+}
+
+func (a *accumulator) add(value string) {
+	if value == "" {
+		return
+	}
+	a.count++
+	if a.count == 1 || value < a.min {
+		a.min = value
+	}
+	if a.count == 1 || value > a.max {
+		a.max = value
+	}
+	number, ok := parseNumber(value)
+	if !ok {
+		a.nonNumeric = true
+		return
+	}
+	a.addNumber(number)
+}
+
+func (a *accumulator) addNumber(number *big.Rat) {
+	if a.sum == nil {
+		a.sum = new(big.Rat)
+	}
+	a.sum.Add(a.sum, number)
+	if a.minNumber == nil || number.Cmp(a.minNumber) < 0 {
+		a.minNumber = number
+	}
+	if a.maxNumber == nil || number.Cmp(a.maxNumber) > 0 {
+		a.maxNumber = number
+	}
+}
+
+func (a *accumulator) merge(other *accumulator) {
+	if other.count == 0 {
+		return
+	}
+	if a.count == 0 || other.min < a.min {
+		a.min = other.min
+	}
+	if a.count == 0 || other.max > a.max {
+		a.max = other.max
+	}
+	a.count += other.count
+	a.nonNumeric = a.nonNumeric || other.nonNumeric
+	if other.sum != nil {
+		if a.sum == nil {
+			a.sum = new(big.Rat)
+		}
+		a.sum.Add(a.sum, other.sum)
+	}
+	if other.minNumber != nil && (a.minNumber == nil || other.minNumber.Cmp(a.minNumber) < 0) {
+		a.minNumber = other.minNumber
+	}
+	if other.maxNumber != nil && (a.maxNumber == nil || other.maxNumber.Cmp(a.maxNumber) > 0) {
+		a.maxNumber = other.maxNumber
+	}
+}
+
+// value returns the result of the aggregate function, the min/max of the numbers are returned only if all the values
+// are numbers.
+func (a *accumulator) value(function string) string {
+	switch function {
+	case AggregationCount:
+		return strconv.FormatInt(a.count, 10)
+	case AggregationSum:
+		return decimalString(a.sum)
+	case AggregationMin:
+		if a.nonNumeric {
+			return a.min
+		}
+		return decimalString(a.minNumber)
+	case AggregationMax:
+		if a.nonNumeric {
+			return a.max
+		}
+		return decimalString(a.maxNumber)
+	}
+	return ""
+}
+
+func (a *accumulator) fields() []string {
+	return []string{
+		strconv.FormatInt(a.count, 10),
+		ratString(a.sum),
+		a.min,
+		a.max,
+		ratString(a.minNumber),
+		ratString(a.maxNumber),
+		strconv.FormatBool(a.nonNumeric),
+	}
+}
+
+func parseAccumulator(fields []string) (*accumulator, error) {
+	count, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	nonNumeric, err := strconv.ParseBool(fields[6])
+	if err != nil {
+		return nil, err
+	}
+	return &accumulator{
+		count:      count,
+		sum:        parseRat(fields[1]),
+		min:        fields[2],
+		max:        fields[3],
+		minNumber:  parseRat(fields[4]),
+		maxNumber:  parseRat(fields[5]),
+		nonNumeric: nonNumeric,
+	}, nil
+}
+
+// parseNumber parses the DynamoDB number string exactly, i.e. with no precision lost.
+func parseNumber(value string) (*big.Rat, bool) {
+	if !IsNumber(value) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(value)
+}
+
+func ratString(r *big.Rat) string {
+	if r == nil {
+		return ""
+	}
+	return r.String()
+}
+
+func parseRat(value string) *big.Rat {
+	if value == "" {
+		return nil
+	}
+	r, _ := new(big.Rat).SetString(value)
+	return r
+}
+
+// decimalString formats the number with as many decimal digits as it needs to be exact. The numbers are either parsed
+// from or computed out of the decimal strings, so their denominators have no other prime factors than 2 and 5.
+func decimalString(r *big.Rat) string {
+	if r == nil {
+		return ""
+	}
+	denominator := new(big.Int).Set(r.Denom())
+	two, five, remainder := big.NewInt(2), big.NewInt(5), new(big.Int)
+	twos, fives := 0, 0
+	for remainder.Mod(denominator, two).Sign() == 0 {
+		denominator.Quo(denominator, two)
+		twos++
+	}
+	for remainder.Mod(denominator, five).Sign() == 0 {
+		denominator.Quo(denominator, five)
+		fives++
+	}
+	digits := twos
+	if fives > digits {
+		digits = fives
+	}
+	return r.FloatString(digits)
+}
+
+type group struct {
+	key          []string
+	rows         int64
+	accumulators []*accumulator
+}
+
+func (g *group) merge(other *group) {
+	g.rows += other.rows
+	for i, a := range g.accumulators {
+		a.merge(other.accumulators[i])
+	}
+}
+
+// GroupWriter aggregates the records by the values of the group by attributes, and writes the single record per group
+// (ordered by the group values) into the underlying writer on Close. If there are more groups than could be kept in
+// memory, they are spilled into the temp files, and merged back on Close.
+type GroupWriter struct {
+	writer       Writer
+	groupBy      []string
+	aggregations []Aggregation
+	maxGroups    int
+	groupIndexes []int
+	indexes      []int
+	groups       map[string]*group
+	dir          string
+	spills       []string
+}
+
+// NewGroupWriter returns the writer which groups the records by the groupBy attributes, and aggregates each group,
+// keeping at most maxGroups groups in memory.
+func NewGroupWriter(writer Writer, groupBy []string, aggregations []Aggregation, maxGroups int) *GroupWriter {
+	return &GroupWriter{
+		writer:       writer,
+		groupBy:      groupBy,
+		aggregations: aggregations,
+		maxGroups:    maxGroups,
+		groups:       make(map[string]*group),
+	}
+}
+
+// WriteHeader resolves the positions of the group by and aggregated attributes in the records.
+func (w *GroupWriter) WriteHeader(attributes []string) error {
+	positions := make(map[string]int, len(attributes))
+	for i, attribute := range attributes {
+		positions[attribute] = i
+	}
+	position := func(attribute string) int {
+		if i, ok := positions[attribute]; ok {
+			return i
+		}
+		return -1
+	}
+	w.groupIndexes = make([]int, 0, len(w.groupBy))
+	for _, attribute := range w.groupBy {
+		w.groupIndexes = append(w.groupIndexes, position(attribute))
+	}
+	w.indexes = make([]int, 0, len(w.aggregations))
+	for _, a := range w.aggregations {
+		w.indexes = append(w.indexes, position(a.Attribute))
+	}
+	return nil
+}
+
+func (w *GroupWriter) Write(record []string) error {
+	key := make([]string, 0, len(w.groupIndexes))
+	for _, i := range w.groupIndexes {
+		key = append(key, field(record, i))
+	}
+	id := strings.Join(key, groupKeySeparator)
+	g, ok := w.groups[id]
+	if !ok {
+		g = w.newGroup(key)
+		w.groups[id] = g
+	}
+	g.rows++
+	for i, a := range g.accumulators {
+		a.add(field(record, w.indexes[i]))
+	}
+	if w.maxGroups > 0 && len(w.groups) > w.maxGroups {
+		return w.spill()
+	}
+	return nil
+}
+
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return record[i]
+}
+
+func (w *GroupWriter) newGroup(key []string) *group {
+	g := &group{key: key, accumulators: make([]*accumulator, 0, len(w.aggregations))}
+	for range w.aggregations {
+		g.accumulators = append(g.accumulators, &accumulator{})
+	}
+	return g
+}
+
+// Flush does nothing, as the groups are written only on Close.
+func (w *GroupWriter) Flush() error {
+	return nil
+}
+
+// sorted returns the groups kept in memory ordered by their group values.
+func (w *GroupWriter) sorted() []*group {
+	groups := make([]*group, 0, len(w.groups))
+	for _, g := range w.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return compareKeys(groups[i].key, groups[j].key) < 0
+	})
+	return groups
+}
+
+func compareKeys(a []string, b []string) int {
+	for i := range a {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// spill writes the groups kept in memory into the next temp file ordered by the group values, so all the files could
+// be merged in a single pass.
+func (w *GroupWriter) spill() error {
+	if w.dir == "" {
+		dir, err := ioutil.TempDir("", "dynocsv-group")
+		if err != nil {
+			return err
+		}
+		w.dir = dir
+	}
+	filename := filepath.Join(w.dir, fmt.Sprintf(spillFileFormat, len(w.spills)+1))
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	encoder := csv.NewWriter(bufio.NewWriter(file))
+	for _, g := range w.sorted() {
+		record := append(append([]string{}, g.key...), strconv.FormatInt(g.rows, 10))
+		for _, a := range g.accumulators {
+			record = append(record, a.fields()...)
+		}
+		if err := encoder.Write(record); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	encoder.Flush()
+	if err := encoder.Error(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	w.spills = append(w.spills, filename)
+	w.groups = make(map[string]*group)
+	return nil
+}
+
+// Close writes the header and the aggregated groups into the underlying writer, and removes the spilled files.
+func (w *GroupWriter) Close() error {
+	header := append([]string{}, w.groupBy...)
+	for _, a := range w.aggregations {
+		header = append(header, a.String())
+	}
+	if err := w.writer.WriteHeader(header); err != nil {
+		return err
+	}
+	var err error
+	if len(w.spills) == 0 {
+		for _, g := range w.sorted() {
+			if err = w.writeGroup(g); err != nil {
+				break
+			}
+		}
+	} else {
+		err = w.merge()
+	}
+	if w.dir != "" {
+		_ = os.RemoveAll(w.dir)
+	}
+	if err != nil {
+		return err
+	}
+	return w.writer.Flush()
+}
+
+func (w *GroupWriter) writeGroup(g *group) error {
+	record := append([]string{}, g.key...)
+	for i, a := range w.aggregations {
+		if a.Function == AggregationCount && a.Attribute == "" {
+			record = append(record, strconv.FormatInt(g.rows, 10))
+			continue
+		}
+		record = append(record, g.accumulators[i].value(a.Function))
+	}
+	return w.writer.Write(record)
+}
+
+type spillReader struct {
+	file    *os.File
+	decoder *csv.Reader
+	next    *group
+}
+
+func (r *spillReader) read(keys int, accumulators int) error {
+	record, err := r.decoder.Read()
+	if err == io.EOF {
+		r.next = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(record) != keys+1+accumulators*accumulatorFields {
+		return fmt.Errorf("corrupted spill file %s", r.file.Name())
+	}
+	rows, err := strconv.ParseInt(record[keys], 10, 64)
+	if err != nil {
+		return err
+	}
+	g := &group{key: record[:keys], rows: rows, accumulators: make([]*accumulator, 0, accumulators)}
+	for i := 0; i < accumulators; i++ {
+		start := keys + 1 + i*accumulatorFields
+		a, err := parseAccumulator(record[start : start+accumulatorFields])
+		if err != nil {
+			return err
+		}
+		g.accumulators = append(g.accumulators, a)
+	}
+	r.next = g
+	return nil
+}
+
+// merge spills the remaining groups, and merges all the spilled files, combining the same groups from the different
+// files.
+func (w *GroupWriter) merge() error {
+	if len(w.groups) != 0 {
+		if err := w.spill(); err != nil {
+			return err
+		}
+	}
+	keys, accumulators := len(w.groupBy), len(w.aggregations)
+	readers := make([]*spillReader, 0, len(w.spills))
+	defer func() {
+		for _, r := range readers {
+			_ = r.file.Close()
+		}
+	}()
+	for _, filename := range w.spills {
+		file, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		r := &spillReader{file: file, decoder: csv.NewReader(bufio.NewReader(file))}
+		readers = append(readers, r)
+		if err := r.read(keys, accumulators); err != nil {
+			return err
+		}
+	}
+	for {
+		var g *group
+		for _, r := range readers {
+			if r.next != nil && (g == nil || compareKeys(r.next.key, g.key) < 0) {
+				g = r.next
+			}
+		}
+		if g == nil {
+			return nil
+		}
+		key := g.key
+		merged := w.newGroup(key)
+		for _, r := range readers {
+			for r.next != nil && compareKeys(r.next.key, key) == 0 {
+				merged.merge(r.next)
+				if err := r.read(keys, accumulators); err != nil {
+					return err
+				}
+			}
+		}
+		if err := w.writeGroup(merged); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package output
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseAggregations(t *testing.T) {
+	tests := []struct {
+		name         string
+		aggregations string
+		want         []Aggregation
+		wantErr      bool
+	}{
+		{
+			name:         "all functions",
+			aggregations: "count, count(email),sum(amount),min(ts),max(ts)",
+			want: []Aggregation{
+				{Function: AggregationCount},
+				{Function: AggregationCount, Attribute: "email"},
+				{Function: AggregationSum, Attribute: "amount"},
+				{Function: AggregationMin, Attribute: "ts"},
+				{Function: AggregationMax, Attribute: "ts"},
+			},
+		},
+		{name: "unknown function", aggregations: "avg(amount)", wantErr: true},
+		{name: "missing attribute", aggregations: "sum", wantErr: true},
+		{name: "empty", aggregations: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAggregations(tt.aggregations)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAggregations() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAggregations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupWriter(t *testing.T) {
+	aggregations, _ := ParseAggregations("count,count(note),sum(amount),min(amount),max(amount),min(ts),max(ts)")
+	records := [][]string{
+		{"acme", "10.10", "2020-05-02"},
+		{"beta", "1", "2020-05-01"},
+		{"acme", "0.000000000000000000000000000000000001", "2020-05-01"},
+		{"acme", "-2", "2020-05-03", "x"},
+		{"", "", ""},
+		{"beta", "12345678901234567890123456789012345678", "2020-04-30", ""},
+	}
+	want := "tenant,count,count(note),sum(amount),min(amount),max(amount),min(ts),max(ts)\n" +
+		",1,0,,,,,\n" +
+		"acme,3,1,8.100000000000000000000000000000000001,-2,10.1,2020-05-01,2020-05-03\n" +
+		"beta,2,0,12345678901234567890123456789012345679,1,12345678901234567890123456789012345678,2020-04-30,2020-05-01\n"
+	for _, maxGroups := range []int{0, 1} {
+		var b bytes.Buffer
+		w := NewGroupWriter(NewCSVWriter(&b), []string{"tenant"}, aggregations, maxGroups)
+		_ = w.WriteHeader([]string{"tenant", "amount", "ts"})
+		for i, record := range records {
+			if i == 3 {
+				// the late attribute has been detected
+				_ = w.WriteHeader([]string{"tenant", "amount", "ts", "note"})
+			}
+			if err := w.Write(record); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if maxGroups == 1 && len(w.spills) == 0 {
+			t.Errorf("GroupWriter spills = %v, want groups spilled", w.spills)
+		}
+		if got := b.String(); got != want {
+			t.Errorf("GroupWriter(maxGroups = %d) output = %q, want %q", maxGroups, got, want)
+		}
+	}
+}
+
+func TestGroupWriterMixedTypes(t *testing.T) {
+	aggregations, _ := ParseAggregations("sum(v),min(v),max(v)")
+	var b bytes.Buffer
+	w := NewGroupWriter(NewCSVWriter(&b), []string{"k"}, aggregations, 0)
+	_ = w.WriteHeader([]string{"k", "v"})
+	for _, v := range []string{"9", "10", "n/a"} {
+		_ = w.Write([]string{"a", v})
+	}
+	_ = w.Close()
+	// non-number values are skipped by sum, and make min/max compare all values as strings
+	want := "k,sum(v),min(v),max(v)\na,19,10,n/a\n"
+	if got := b.String(); got != want {
+		t.Errorf("GroupWriter output = %q, want %q", got, want)
+	}
+}
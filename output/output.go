@@ -0,0 +1,90 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Writer writes the exported records (with the corresponding header) into the output.
+type Writer interface {
+	// WriteHeader sets the header of the output. The first call writes the header, any subsequent call reports the
+	// header has been extended with the newly detected attributes after the first records have been already written.
+	WriteHeader(attributes []string) error
+	Write(record []string) error
+	Flush() error
+}
+
+// CSVWriter writes all the records into the single CSV output.
+type CSVWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+// NewCSVWriter returns the writer which writes CSV records into w.
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{writer: csv.NewWriter(w)}
+}
+
+// NewAppendCSVWriter returns the writer which appends CSV records into w, which already has the header written.
+func NewAppendCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{writer: csv.NewWriter(w), headerWritten: true}
+}
+
+// WriteHeader writes the header only once, as there is no way to update the header already written into the output.
+func (w *CSVWriter) WriteHeader(attributes []string) error {
+	if w.headerWritten {
+		return nil
+	}
+	w.headerWritten = true
+	return w.writer.Write(attributes)
+}
+
+func (w *CSVWriter) Write(record []string) error {
+	return w.writer.Write(record)
+}
+
+// Flush writes any buffered data into the underlying output.
+func (w *CSVWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+var numberPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// IsNumber returns whether the value is the number as DynamoDB accepts it, i.e. "10", "-1.5" or "1e3", so the values
+// are classified the same way wherever they are parsed (the filter, the import, the group by, etc.).
+func IsNumber(value string) bool {
+	return numberPattern.MatchString(value)
+}
+
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{suffix: "GB", multiplier: 1 << 30},
+	{suffix: "MB", multiplier: 1 << 20},
+	{suffix: "KB", multiplier: 1 << 10},
+	{suffix: "B", multiplier: 1},
+}
+
+// ParseSize parses the human readable size, i.e. "500MB", "10KB" or "1024" (bytes), into the number of bytes.
+func ParseSize(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q, expected positive number optionally followed by B, KB, MB or GB", size)
+	}
+	return n * multiplier, nil
+}
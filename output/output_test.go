@@ -0,0 +1,92 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{name: "bytes without unit", size: "1024", want: 1024},
+		{name: "bytes", size: "10B", want: 10},
+		{name: "kilobytes", size: "10KB", want: 10 << 10},
+		{name: "megabytes", size: "500MB", want: 500 << 20},
+		{name: "gigabytes", size: "2GB", want: 2 << 30},
+		{name: "lower case unit", size: "500mb", want: 500 << 20},
+		{name: "space before unit", size: "500 MB", want: 500 << 20},
+		{name: "unknown unit", size: "500TB", wantErr: true},
+		{name: "zero size", size: "0MB", wantErr: true},
+		{name: "negative size", size: "-1MB", wantErr: true},
+		{name: "empty size", size: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSize(tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSize() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "10", want: true},
+		{value: "-1.5", want: true},
+		{value: "+.5", want: true},
+		{value: "1e3", want: true},
+		{value: "2.5E-10", want: true},
+		{value: "10.", want: true},
+		{value: "", want: false},
+		{value: ".", want: false},
+		{value: "1e", want: false},
+		{value: "0x10", want: false},
+		{value: "10 ", want: false},
+		{value: "NaN", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := IsNumber(tt.value); got != tt.want {
+				t.Errorf("IsNumber(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVWriterWriteHeaderOnce(t *testing.T) {
+	var b bytes.Buffer
+	w := NewCSVWriter(&b)
+	_ = w.WriteHeader([]string{"A", "B"})
+	_ = w.Write([]string{"a", "b"})
+	_ = w.WriteHeader([]string{"A", "B", "C"})
+	_ = w.Write([]string{"a", "b", "c"})
+	_ = w.Flush()
+	want := "A,B\na,b\na,b,c\n"
+	if got := b.String(); got != want {
+		t.Errorf("CSVWriter output = %q, want %q", got, want)
+	}
+}
+
+func TestAppendCSVWriterSkipsHeader(t *testing.T) {
+	var b bytes.Buffer
+	w := NewAppendCSVWriter(&b)
+	_ = w.WriteHeader([]string{"A", "B"})
+	_ = w.Write([]string{"a", "b"})
+	_ = w.Flush()
+	want := "a,b\n"
+	if got := b.String(); got != want {
+		t.Errorf("CSVWriter output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RollingWriter appends CSV records into the file, continuing with the header already written into it. Once the header
+// is extended (i.e. the new attribute is detected after the records have been already written), the file is rolled
+// over: it is renamed into the next free <name>-00001.csv, <name>-00002.csv, etc., and the file is started again with
+// the extended header, so each file is consistent with its own header, and the records are still appended into the
+// same file.
+type RollingWriter struct {
+	filename      string
+	header        []string
+	headerWritten bool
+	file          *os.File
+	writer        *csv.Writer
+	parts         []string
+}
+
+// NewRollingWriter returns the writer which appends into the filename, the file is created if it doesn't exist yet.
+func NewRollingWriter(filename string) (*RollingWriter, error) {
+	w := &RollingWriter{filename: filename}
+	file, err := os.Open(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		header, err := csv.NewReader(file).Read()
+		file.Close()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		w.header, w.headerWritten = header, header != nil
+	}
+	if err := w.open(os.O_APPEND); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Header returns the header already written into the file, or nil if there is none yet.
+func (w *RollingWriter) Header() []string {
+	return w.header
+}
+
+// WriteHeader writes the header into the empty file, or rolls the file over if the header differs from the one
+// already written.
+func (w *RollingWriter) WriteHeader(attributes []string) error {
+	if w.headerWritten && equal(w.header, attributes) {
+		return nil
+	}
+	if w.headerWritten {
+		if err := w.roll(); err != nil {
+			return err
+		}
+	}
+	w.header = append([]string(nil), attributes...)
+	w.headerWritten = true
+	return w.writer.Write(w.header)
+}
+
+func (w *RollingWriter) Write(record []string) error {
+	return w.writer.Write(record)
+}
+
+// Flush writes any buffered data into the file.
+func (w *RollingWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// Close flushes and closes the file.
+func (w *RollingWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// Parts returns the files the records have been rolled over into, in the order they were rolled over.
+func (w *RollingWriter) Parts() []string {
+	return w.parts
+}
+
+func (w *RollingWriter) roll() error {
+	if err := w.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(w.filename)
+	base := strings.TrimSuffix(w.filename, ext)
+	for n := 1; ; n++ {
+		name := fmt.Sprintf(partNumberFormat, base, n, ext)
+		if _, err := os.Stat(name); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Rename(w.filename, name); err != nil {
+			return err
+		}
+		w.parts = append(w.parts, name)
+		return w.open(os.O_TRUNC)
+	}
+}
+
+func (w *RollingWriter) open(flag int) error {
+	file, err := os.OpenFile(w.filename, os.O_WRONLY|os.O_CREATE|flag, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.writer = csv.NewWriter(file)
+	return nil
+}
+
+func equal(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRollingWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "t.csv")
+	// the part already rolled over into by the previous run is kept
+	if err := ioutil.WriteFile(filepath.Join(dir, "t-00001.csv"), []byte("Id\n0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte("Id\n1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRollingWriter(filename)
+	if err != nil {
+		t.Fatalf("NewRollingWriter() error = %v", err)
+	}
+	if want := []string{"Id"}; !reflect.DeepEqual(w.Header(), want) {
+		t.Errorf("Header() = %v, want %v", w.Header(), want)
+	}
+	steps := []struct {
+		header []string
+		record []string
+	}{
+		{header: []string{"Id"}, record: []string{"2"}},
+		{header: []string{"Id", "Name"}, record: []string{"3", "a"}},
+		{header: []string{"Id", "Name"}, record: []string{"4", "b"}},
+		{header: []string{"Id", "Name", "Total"}, record: []string{"5", "c", "10"}},
+	}
+	for _, step := range steps {
+		if err := w.WriteHeader(step.header); err != nil {
+			t.Fatalf("WriteHeader() error = %v", err)
+		}
+		if err := w.Write(step.record); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	wantParts := []string{filepath.Join(dir, "t-00002.csv"), filepath.Join(dir, "t-00003.csv")}
+	if !reflect.DeepEqual(w.Parts(), wantParts) {
+		t.Errorf("Parts() = %v, want %v", w.Parts(), wantParts)
+	}
+	want := map[string]string{
+		"t-00001.csv": "Id\n0\n",
+		"t-00002.csv": "Id\n1\n2\n",
+		"t-00003.csv": "Id,Name\n3,a\n4,b\n",
+		"t.csv":       "Id,Name,Total\n5,c,10\n",
+	}
+	for name, content := range want {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+	}
+}
+
+func TestRollingWriterNewFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynocsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "t.csv")
+	w, err := NewRollingWriter(filename)
+	if err != nil {
+		t.Fatalf("NewRollingWriter() error = %v", err)
+	}
+	if w.Header() != nil {
+		t.Errorf("Header() = %v, want nil", w.Header())
+	}
+	_ = w.WriteHeader([]string{"Id"})
+	_ = w.Write([]string{"1"})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	data, _ := ioutil.ReadFile(filename)
+	if string(data) != "Id\n1\n" || w.Parts() != nil {
+		t.Errorf("file = %q, parts = %v, want %q and no parts", data, w.Parts(), "Id\n1\n")
+	}
+}
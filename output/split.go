@@ -0,0 +1,168 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	partNumberFormat = "%s-%05d%s"
+	manifestSuffix   = "-manifest.json"
+)
+
+// Part describes the single file produced by the SplitWriter.
+type Part struct {
+	File   string   `json:"file"`
+	Rows   uint     `json:"rows"`
+	Bytes  int64    `json:"bytes"`
+	Header []string `json:"header"`
+}
+
+// Manifest lists all the parts produced by the SplitWriter in the order they were written.
+type Manifest struct {
+	Parts []Part `json:"parts"`
+}
+
+// SplitWriter writes CSV records into the sequence of files named <name>-00001.csv, <name>-00002.csv, etc., rolling
+// over into the next file once either the rows or the size limit of the current file is reached (0 means no limit).
+// Each file starts with the header. If the header changes (i.e. new attribute is detected after the records have been
+// already written), the writer rolls over into the next file, so each file is consistent with its own header.
+type SplitWriter struct {
+	base    string
+	ext     string
+	rows    uint
+	size    int64
+	header  []string
+	rolling bool
+	file    *os.File
+	buffer  *bufio.Writer
+	part    *Part
+	parts   []Part
+	scratch bytes.Buffer
+	encoder *csv.Writer
+}
+
+// NewSplitWriter returns the writer which splits the output filename into the parts by the rows and/or size limits.
+func NewSplitWriter(filename string, rows uint, size int64) *SplitWriter {
+	ext := filepath.Ext(filename)
+	w := &SplitWriter{base: strings.TrimSuffix(filename, ext), ext: ext, rows: rows, size: size}
+	w.encoder = csv.NewWriter(&w.scratch)
+	return w
+}
+
+// WriteHeader sets the header used for the current and all the next parts. If the current part already has records,
+// the writer rolls over into the next part on the next record.
+func (w *SplitWriter) WriteHeader(attributes []string) error {
+	w.header = append([]string(nil), attributes...)
+	w.rolling = w.part != nil
+	return nil
+}
+
+func (w *SplitWriter) Write(record []string) error {
+	data, err := w.encode(record)
+	if err != nil {
+		return err
+	}
+	if w.part == nil || w.rolling || w.exceeds(int64(len(data))) {
+		if err := w.next(); err != nil {
+			return err
+		}
+		// the scratch buffer has been reused to encode the header of the new part
+		if data, err = w.encode(record); err != nil {
+			return err
+		}
+	}
+	n, err := w.buffer.Write(data)
+	w.part.Bytes += int64(n)
+	w.part.Rows++
+	return err
+}
+
+// Flush writes any buffered data into the current part.
+func (w *SplitWriter) Flush() error {
+	if w.buffer == nil {
+		return nil
+	}
+	return w.buffer.Flush()
+}
+
+// Close closes the current part and writes the manifest listing all the parts.
+func (w *SplitWriter) Close() error {
+	if len(w.parts) == 0 && w.part == nil && w.header != nil {
+		// no records have been written, but still produce the single part with the header only
+		if err := w.next(); err != nil {
+			return err
+		}
+	}
+	if err := w.closePart(); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(Manifest{Parts: w.parts}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(w.ManifestFile(), data, 0666)
+}
+
+// ManifestFile returns the name of the manifest file.
+func (w *SplitWriter) ManifestFile() string {
+	return w.base + manifestSuffix
+}
+
+func (w *SplitWriter) exceeds(n int64) bool {
+	if w.part.Rows == 0 {
+		return false
+	}
+	return (w.rows > 0 && w.part.Rows >= w.rows) || (w.size > 0 && w.part.Bytes+n > w.size)
+}
+
+func (w *SplitWriter) encode(record []string) ([]byte, error) {
+	w.scratch.Reset()
+	_ = w.encoder.Write(record)
+	w.encoder.Flush()
+	if err := w.encoder.Error(); err != nil {
+		return nil, err
+	}
+	return w.scratch.Bytes(), nil
+}
+
+func (w *SplitWriter) next() error {
+	if err := w.closePart(); err != nil {
+		return err
+	}
+	name := fmt.Sprintf(partNumberFormat, w.base, len(w.parts)+1, w.ext)
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.buffer = bufio.NewWriter(file)
+	w.part = &Part{File: name, Header: w.header}
+	w.rolling = false
+	header, err := w.encode(w.header)
+	if err != nil {
+		return err
+	}
+	n, err := w.buffer.Write(header)
+	w.part.Bytes += int64(n)
+	return err
+}
+
+func (w *SplitWriter) closePart() error {
+	if w.part == nil {
+		return nil
+	}
+	w.parts = append(w.parts, *w.part)
+	w.part = nil
+	if err := w.buffer.Flush(); err != nil {
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,123 @@
+package output
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    uint
+		size    int64
+		records [][]string
+		want    map[string]string
+	}{
+		{
+			name:    "split by rows",
+			rows:    2,
+			records: [][]string{{"1"}, {"2"}, {"3"}},
+			want: map[string]string{
+				"t-00001.csv": "Id\n1\n2\n",
+				"t-00002.csv": "Id\n3\n",
+			},
+		},
+		{
+			name:    "split by size",
+			size:    8,
+			records: [][]string{{"1"}, {"2"}, {"3"}},
+			want: map[string]string{
+				"t-00001.csv": "Id\n1\n2\n",
+				"t-00002.csv": "Id\n3\n",
+			},
+		},
+		{
+			name:    "record larger than size is still written",
+			size:    4,
+			records: [][]string{{"12345"}, {"6"}},
+			want: map[string]string{
+				"t-00001.csv": "Id\n12345\n",
+				"t-00002.csv": "Id\n6\n",
+			},
+		},
+		{
+			name:    "no records",
+			rows:    2,
+			records: [][]string{},
+			want: map[string]string{
+				"t-00001.csv": "Id\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, _ := ioutil.TempDir("", "dynocsv")
+			defer os.RemoveAll(dir)
+			w := NewSplitWriter(filepath.Join(dir, "t.csv"), tt.rows, tt.size)
+			_ = w.WriteHeader([]string{"Id"})
+			for _, record := range tt.records {
+				if err := w.Write(record); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			assertFiles(t, dir, tt.want)
+			// parts + manifest
+			assertFilesCount(t, dir, len(tt.want)+1)
+		})
+	}
+}
+
+func TestSplitWriterRollsOverOnHeaderChange(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "dynocsv")
+	defer os.RemoveAll(dir)
+	w := NewSplitWriter(filepath.Join(dir, "t.csv"), 10, 0)
+	_ = w.WriteHeader([]string{"Id"})
+	_ = w.Write([]string{"1"})
+	_ = w.WriteHeader([]string{"Id", "A"})
+	_ = w.Write([]string{"2", "a"})
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	assertFiles(t, dir, map[string]string{
+		"t-00001.csv": "Id\n1\n",
+		"t-00002.csv": "Id,A\n2,a\n",
+	})
+	assertFilesCount(t, dir, 3)
+	data, _ := ioutil.ReadFile(w.ManifestFile())
+	manifest := Manifest{}
+	_ = json.Unmarshal(data, &manifest)
+	want := []Part{
+		{File: filepath.Join(dir, "t-00001.csv"), Rows: 1, Bytes: 5, Header: []string{"Id"}},
+		{File: filepath.Join(dir, "t-00002.csv"), Rows: 1, Bytes: 9, Header: []string{"Id", "A"}},
+	}
+	if !reflect.DeepEqual(manifest.Parts, want) {
+		t.Errorf("manifest parts = %v, want %v", manifest.Parts, want)
+	}
+}
+
+func assertFiles(t *testing.T, dir string, want map[string]string) {
+	for name, content := range want {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("failed to read %s: %v", name, err)
+			continue
+		}
+		if got := string(data); got != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+}
+
+func assertFilesCount(t *testing.T, dir string, want int) {
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != want {
+		t.Errorf("files count = %d, want %d", len(files), want)
+	}
+}
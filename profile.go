@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+)
+
+const (
+	sampleFlagName = "sample"
+
+	profileCommandName = "profile"
+)
+
+func profileCommand() cli.Command {
+	return cli.Command{
+		Name: profileCommandName,
+		Usage: "report each attribute's presence, type distribution, distinct values estimate, min/max length or " +
+			"value and example values",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t                                     <table>
+        [--index/-i                                    <index to query or scan instead of table>]
+        [--hash                                        <hash value>]
+        [--sort                                        <sort value>]
+        [--sort-[gt, ge, lt, le, begins-with, between] <sort value>]
+        [--since                                       <duration, i.e. 24h, or time>]
+        [--until                                       <duration, i.e. 24h, or time>]
+        [--sort-format                                 <iso, epoch or epoch-ms>]
+        [--filter                                      <attribute><operator><value>]...
+        [--segments                                    <number of parallel scan segments>]
+        [--sample                                      <number>]
+        [--json]`,
+			appName, profileCommandName),
+		Flags: append(append([]cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table to profile",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, i", indexFlagName),
+				Usage: "index to query or scan instead of table",
+			},
+		}, queryFlags()...),
+			cli.UintFlag{
+				Name:  fmt.Sprintf("%s", segmentsFlagName),
+				Usage: "number of segments the scan is split into and run in parallel (not used by the query)",
+				Value: defaultSegments,
+			},
+			cli.UintFlag{
+				Name:  fmt.Sprintf("%s", sampleFlagName),
+				Usage: "profile only the first number of items, if not set (i.e. 0) all items are profiled",
+			},
+			cli.BoolFlag{
+				Name:  fmt.Sprintf("%s", jsonFlagName),
+				Usage: "output the profile as JSON",
+			},
+		),
+		Action: profile,
+	}
+}
+
+func profile(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	qp, err := queryParams(c)
+	if err != nil {
+		return err
+	}
+	filter, err := dynamodb.ParseFilter(c.StringSlice(filterFlagName))
+	if err != nil {
+		return err
+	}
+	tp, err := dynamodb.ProfileTable(sessionParams(c, cfg), &dynamodb.ProfileParams{
+		Table:    mustFlag(c, tableFlagName),
+		Index:    c.String(indexFlagName),
+		Query:    qp,
+		Filter:   filter,
+		Segments: c.Uint(segmentsFlagName),
+		Sample:   c.Uint(sampleFlagName),
+	})
+	if err != nil {
+		return err
+	}
+	if c.Bool(jsonFlagName) {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(tp)
+	}
+	return tp.Print(os.Stdout)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	startFromFlagName  = "start-from"
+	formatFlagName     = "format"
+	checkpointFlagName = "checkpoint"
+	idleFlagName       = "idle"
+
+	tailCommandName = "tail"
+)
+
+func tailCommand() cli.Command {
+	return cli.Command{
+		Name:  tailCommandName,
+		Usage: "follow the table's stream, and append the changes as CSV or JSON lines",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t     <table>
+        [--start-from  <trim-horizon or latest>]
+        [--format      <csv or jsonl>]
+        [--output/-o   <file to append to, or "-" for stdout>]
+        [--checkpoint  <file to save the shards sequence numbers into and resume from>]
+        [--limit/-l    <number>]
+        [--idle        <duration, i.e. 1m>]`,
+			appName, tailCommandName),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table which stream to follow",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", startFromFlagName),
+				Usage: fmt.Sprintf("where to start reading the stream from unless resumed from the checkpoint, one of "+
+					"%s (the oldest records kept by the stream for 24 hours)",
+					strings.Join(dynamodb.TailStartPositions, ", ")),
+				Value: dynamodb.TailLatest,
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", formatFlagName),
+				Usage: fmt.Sprintf("format of the records, one of %s, the CSV has the new and old images attributes "+
+					"as \"new:\" and \"old:\" prefixed columns", strings.Join(dynamodb.TailFormats, ", ")),
+				Value: dynamodb.TailFormatCSV,
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s, o", outputFlagName),
+				Usage: "file to append the records to, the CSV columns are the ones of the header already written " +
+					"into it, and the file is rolled over into <name>-00001.csv, etc. once the new attribute extends " +
+					"the header, otherwise stdout is used",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", checkpointFlagName),
+				Usage: "file to save the sequence numbers of the records written into, and to resume the tail from",
+			},
+			cli.UintFlag{
+				Name:  fmt.Sprintf("%s, l", limitFlagName),
+				Usage: "stop once the number of records is written, if not set (i.e. 0) the tail runs until stopped",
+			},
+			cli.DurationFlag{
+				Name:  fmt.Sprintf("%s", idleFlagName),
+				Usage: "stop once there were no new records for the duration, if not set the tail runs until stopped",
+			},
+		},
+		Action: tail,
+	}
+}
+
+func tail(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	table := mustFlag(c, tableFlagName)
+	startFrom, format := c.String(startFromFlagName), c.String(formatFlagName)
+	if !contains(dynamodb.TailStartPositions, startFrom) {
+		return fmt.Errorf("invalid \"%s\" %q, expected one of %s",
+			startFromFlagName, startFrom, strings.Join(dynamodb.TailStartPositions, ", "))
+	}
+	if !contains(dynamodb.TailFormats, format) {
+		return fmt.Errorf("invalid \"%s\" %q, expected one of %s",
+			formatFlagName, format, strings.Join(dynamodb.TailFormats, ", "))
+	}
+	tp := &dynamodb.TailParams{
+		Table:      table,
+		StartFrom:  startFrom,
+		Format:     format,
+		Checkpoint: c.String(checkpointFlagName),
+		Limit:      c.Uint(limitFlagName),
+		Idle:       c.Duration(idleFlagName),
+		Output:     os.Stdout,
+	}
+	if filename := c.String(outputFlagName); filename != "" && filename != "-" && format == dynamodb.TailFormatCSV {
+		tp.Filename = filename
+	} else if filename != "" && filename != "-" {
+		file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		tp.Output = file
+	}
+	// the tail is stopped gracefully, so the records read are written, and the checkpoint is saved
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+	tp.Stop = stop
+	start := time.Now()
+	result, err := dynamodb.TailStream(sessionParams(c, cfg), tp)
+	if result != nil {
+		// the summary goes into stderr, as stdout could be the output
+		fmt.Fprintf(os.Stderr, "Tailed %d records in %s\n", result.Records, time.Since(start).Round(time.Second))
+		if result.Parts != nil {
+			fmt.Fprintf(os.Stderr, "Rolled over into the new file with the extended CSV header, the previous "+
+				"records are in: %s\n", strings.Join(result.Parts, ","))
+		}
+	}
+	return err
+}
+
+// readHeader returns the CSV header of the file, or nil if the file doesn't exist yet or is empty.
+func readHeader(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	header, err := csv.NewReader(file).Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	return header, err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
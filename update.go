@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"github.com/zshamrock/dynocsv/output"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	conditionFlagName   = "condition"
+	removeEmptyFlagName = "remove-empty"
+	upsertFlagName      = "upsert"
+	reportFlagName      = "report"
+
+	reportSuffix = "-report.csv"
+
+	updateCommandName = "update"
+)
+
+func updateCommand() cli.Command {
+	return cli.Command{
+		Name:  updateCommandName,
+		Usage: "update items of the table identified by the key columns of CSV patch using UpdateItem",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t      <table>
+        --input/-i      <CSV file or "-" for stdin>
+        [--types        <attribute>=<type>[,<attribute>=<type>]...]
+        [--condition    <attribute><operator><value>]...
+        [--remove-empty]
+        [--upsert]
+        [--rate         <max items updated per second>]
+        [--concurrency  <number of items updated at the same time>]
+        [--report       <CSV file to write the result of each row into>]`,
+			appName, updateCommandName),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table to update items of",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s, i", inputFlagName),
+				Usage: "CSV patch file, or \"-\" to read it from stdin, the \"if:<attribute>\" columns are the " +
+					"values the attributes have to have for the item to be updated (the empty value means the " +
+					"attribute has to be missing)",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", typesFlagName),
+				Usage: "attribute types, i.e. \"age=N,tags=SS\" (one of S, N, B, BOOL, NULL, SS, NS, BS, L or M), " +
+					"otherwise the key attributes types are taken from the table, and the rest are inferred from " +
+					"the values",
+			},
+			cli.StringSliceFlag{
+				Name: fmt.Sprintf("%s", conditionFlagName),
+				Usage: "condition each item has to match to be updated, in the same format as the filter, i.e. " +
+					"\"status=NEW\", could be repeated",
+			},
+			cli.BoolFlag{
+				Name:  fmt.Sprintf("%s", removeEmptyFlagName),
+				Usage: "remove the attributes of the empty values, otherwise the empty values are not updated",
+			},
+			cli.BoolFlag{
+				Name:  fmt.Sprintf("%s", upsertFlagName),
+				Usage: "create the items which don't exist, otherwise only the existing items are updated",
+			},
+			cli.Float64Flag{
+				Name:  fmt.Sprintf("%s", rateFlagName),
+				Usage: "max number of items updated per second, 0 means no limit",
+			},
+			cli.UintFlag{
+				Name:  fmt.Sprintf("%s", concurrencyFlagName),
+				Usage: "number of items updated at the same time",
+				Value: defaultConcurrency,
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", reportFlagName),
+				Usage: fmt.Sprintf("CSV file to write the result of each row into, by default <input>%s "+
+					"(or stdout if the input is stdin)", reportSuffix),
+			},
+		},
+		Action: updateCSV,
+	}
+}
+
+func updateCSV(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	types, err := dynamodb.ParseTypes(c.String(typesFlagName))
+	if err != nil {
+		return err
+	}
+	condition, err := dynamodb.ParseFilter(c.StringSlice(conditionFlagName))
+	if err != nil {
+		return err
+	}
+	table, input := mustFlag(c, tableFlagName), mustFlag(c, inputFlagName)
+	var r io.Reader = os.Stdin
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	var w io.Writer = os.Stdout
+	filename := c.String(reportFlagName)
+	if filename == "" && input != "-" {
+		filename = strings.TrimSuffix(input, filepath.Ext(input)) + reportSuffix
+	}
+	if filename != "" && filename != "-" {
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	result, err := dynamodb.UpdateFromCSV(sessionParams(c, cfg), r, &dynamodb.UpdateParams{
+		Table:       table,
+		Types:       types,
+		Condition:   condition,
+		RemoveEmpty: c.Bool(removeEmptyFlagName),
+		Upsert:      c.Bool(upsertFlagName),
+		Rate:        c.Float64(rateFlagName),
+		Concurrency: c.Uint(concurrencyFlagName),
+		Report:      output.NewCSVWriter(w),
+	})
+	if result != nil {
+		// the summary goes into stderr, as stdout could be the report
+		fmt.Fprintf(os.Stderr, "Updated %d items, %d failed the condition, %d skipped, %d failed\n",
+			result.Updated, result.ConditionFailed, result.Skipped, result.Failed)
+	}
+	if err == nil && result.Failed != 0 {
+		err = fmt.Errorf("%d rows failed to update, see the report for the errors", result.Failed)
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"github.com/zshamrock/dynocsv/aws/dynamodb"
+	"github.com/zshamrock/dynocsv/config"
+	"github.com/zshamrock/dynocsv/output"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+)
+
+const validateCommandName = "validate"
+
+func validateCommand() cli.Command {
+	return cli.Command{
+		Name:  validateCommandName,
+		Usage: "validate CSV against the table's key schema, attribute definitions and indexes before import",
+		UsageText: fmt.Sprintf(`%s [global options] %s
+        --table/-t <table>
+        --input/-i <CSV file or "-" for stdin>
+        [--types   <attribute>=<type>[,<attribute>=<type>]...]
+        [--report  <CSV file to write the issues into>]`,
+			appName, validateCommandName),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, t", tableFlagName),
+				Usage: "table the CSV is going to be imported into",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s, i", inputFlagName),
+				Usage: "CSV file to validate, or \"-\" to read it from stdin",
+			},
+			cli.StringFlag{
+				Name: fmt.Sprintf("%s", typesFlagName),
+				Usage: "attribute types the same as the import is going to be run with, i.e. \"age=N,tags=SS\" " +
+					"(one of S, N, B, BOOL, NULL, SS, NS, BS, L or M)",
+			},
+			cli.StringFlag{
+				Name:  fmt.Sprintf("%s", reportFlagName),
+				Usage: "CSV file to write the issues with their line numbers into, otherwise stdout is used",
+			},
+		},
+		Action: validateCSV,
+	}
+}
+
+func validateCSV(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	types, err := dynamodb.ParseTypes(c.String(typesFlagName))
+	if err != nil {
+		return err
+	}
+	table := mustFlag(c, tableFlagName)
+	var r io.Reader = os.Stdin
+	if input := mustFlag(c, inputFlagName); input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	var w io.Writer = os.Stdout
+	if filename := c.String(reportFlagName); filename != "" && filename != "-" {
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	result, err := dynamodb.ValidateCSV(sessionParams(c, cfg), table, r, types, output.NewCSVWriter(w))
+	if result != nil {
+		// the summary goes into stderr, as stdout could be the report
+		fmt.Fprintf(os.Stderr, "Validated %d rows, %d invalid rows, %d issues\n",
+			result.Rows, result.InvalidRows, result.Issues)
+	}
+	if err == nil && result.Issues != 0 {
+		err = fmt.Errorf("%d issues found", result.Issues)
+	}
+	return err
+}